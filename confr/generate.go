@@ -0,0 +1,583 @@
+package confr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"gopkg.in/yaml.v3"
+)
+
+// DescriptionTag is the field tag to describe a configuration field,
+// it is used by GenerateSample, GenerateMarkdown and RegisterFlags.
+const DescriptionTag = "description"
+
+// fieldDoc holds information of a configuration field to generate
+// documentation and sample configuration.
+type fieldDoc struct {
+	field       reflect.StructField
+	path        string
+	value       reflect.Value
+	envNames    []string
+	flagName    string
+	customTag   string
+	defaultVal  string
+	description string
+
+	isGroup  bool
+	children []*fieldDoc
+}
+
+func (p *Loader) collectFieldDocs(config any) ([]*fieldDoc, error) {
+	configVal := reflect.Indirect(reflect.ValueOf(config))
+	if configVal.Kind() != reflect.Struct {
+		return nil, errors.New("invalid config, should be a struct or struct pointer")
+	}
+	return p.walkFieldDocs(configVal, "", ""), nil
+}
+
+func (p *Loader) walkFieldDocs(configVal reflect.Value, path, envPrefix string) []*fieldDoc {
+	configTyp := configVal.Type()
+	var out []*fieldDoc
+	for i := 0; i < configTyp.NumField(); i++ {
+		field := configTyp.Field(i)
+		fieldVal := configVal.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Tag.Get(ConfrTag) == "-" {
+			continue
+		}
+
+		doc := &fieldDoc{
+			field:       field,
+			path:        field.Name,
+			value:       fieldVal,
+			flagName:    field.Tag.Get(FlagTag),
+			customTag:   field.Tag.Get(CustomTag),
+			defaultVal:  field.Tag.Get(DefaultValueTag),
+			description: field.Tag.Get(DescriptionTag),
+		}
+		if path != "" {
+			doc.path = path + "." + field.Name
+		}
+		if doc.flagName == "-" {
+			doc.flagName = ""
+		}
+		if doc.customTag == "-" {
+			doc.customTag = ""
+		}
+
+		// Keep the same rule with processEnv.
+		envTag := field.Tag.Get(EnvTag)
+		if envTag != "" {
+			for _, name := range strings.Split(envTag, ",") {
+				name = strings.TrimSpace(name)
+				if name != "" {
+					doc.envNames = append(doc.envNames, name)
+				}
+			}
+		} else if p.EnableImplicitEnv && !isGroupType(field.Type) {
+			tmp := p.getEnvName(envPrefix, field.Name)
+			doc.envNames = append(doc.envNames, tmp, strings.ToUpper(tmp))
+		}
+
+		if isGroupType(field.Type) {
+			structVal := reflect.Indirect(fieldVal)
+			if !structVal.IsValid() {
+				structVal = reflect.New(field.Type.Elem()).Elem()
+			}
+			doc.isGroup = true
+			doc.children = p.walkFieldDocs(structVal, doc.path, p.getEnvName(envPrefix, field.Name))
+		} else if doc.defaultVal != "" && fieldVal.IsZero() {
+			tmp := reflect.New(field.Type).Elem()
+			if err := assignFieldValue(tmp, doc.defaultVal); err == nil {
+				doc.value = tmp
+			}
+		}
+		out = append(out, doc)
+	}
+	return out
+}
+
+func isGroupType(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || typ == reflect.TypeOf(time.Time{}) {
+		return false
+	}
+	ptrTyp := reflect.PointerTo(typ)
+	for _, iface := range []reflect.Type{
+		reflect.TypeOf((*json.Unmarshaler)(nil)).Elem(),
+		reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem(),
+	} {
+		if ptrTyp.Implements(iface) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *fieldDoc) key(format string) string {
+	name, _, _ := strings.Cut(d.field.Tag.Get(format), ",")
+	if name != "" {
+		return name
+	}
+	if format == FormatYAML {
+		return strings.ToLower(d.field.Name)
+	}
+	return d.field.Name
+}
+
+func (d *fieldDoc) commentLines() []string {
+	var lines []string
+	if d.description != "" {
+		lines = append(lines, strings.Split(d.description, "\n")...)
+	}
+	if d.defaultVal != "" {
+		lines = append(lines, "Default: "+d.defaultVal)
+	}
+	if len(d.envNames) > 0 {
+		lines = append(lines, "Env: "+strings.Join(d.envNames, ", "))
+	}
+	if d.flagName != "" {
+		lines = append(lines, "Flag: -"+d.flagName)
+	}
+	if d.customTag != "" {
+		lines = append(lines, "Custom: "+d.customTag)
+	}
+	return lines
+}
+
+// GenerateSample writes a sample configuration file of the given format
+// for config to w, config should be a struct or struct pointer.
+// format should be one of FormatJSON, FormatTOML and FormatYAML.
+//
+// Values of the sample come from config, fields which have zero value
+// are filled by the `default` tag.
+// For YAML and TOML formats, the `description`, `default`, `env`, `flag`
+// and `custom` tags are written as comments of each field,
+// JSON does not support comments, thus no comments are written.
+func (p *Loader) GenerateSample(w io.Writer, config any, format string) error {
+	docs, err := p.collectFieldDocs(config)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(jsonSampleObject(docs), "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case FormatYAML:
+		node, err := yamlSampleNode(docs)
+		if err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err = enc.Encode(node); err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatTOML:
+		var buf bytes.Buffer
+		writeTOMLSample(&buf, docs, nil)
+		_, err = w.Write(buf.Bytes())
+		return err
+	}
+	return fmt.Errorf("unsupported configuration format: %q", format)
+}
+
+type jsonSampleObject []*fieldDoc
+
+func (obj jsonSampleObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	for _, d := range obj {
+		key := d.key(FormatJSON)
+		if key == "-" {
+			continue
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		keyData, _ := json.Marshal(key)
+		buf.Write(keyData)
+		buf.WriteByte(':')
+		var value any = jsonSampleObject(d.children)
+		if !d.isGroup {
+			value = d.value.Interface()
+		}
+		valueData, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(valueData)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func yamlSampleNode(docs []*fieldDoc) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, d := range docs {
+		key := d.key(FormatYAML)
+		if key == "-" {
+			continue
+		}
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: key}
+		if lines := d.commentLines(); len(lines) > 0 {
+			keyNode.HeadComment = "# " + strings.Join(lines, "\n# ")
+		}
+		var valueNode *yaml.Node
+		var err error
+		if d.isGroup {
+			valueNode, err = yamlSampleNode(d.children)
+		} else {
+			valueNode = &yaml.Node{}
+			err = valueNode.Encode(d.value.Interface())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode field %s: %w", d.path, err)
+		}
+		node.Content = append(node.Content, keyNode, valueNode)
+	}
+	return node, nil
+}
+
+func writeTOMLSample(buf *bytes.Buffer, docs []*fieldDoc, table []string) {
+	writeComments := func(d *fieldDoc) {
+		for _, line := range d.commentLines() {
+			buf.WriteString("# " + line + "\n")
+		}
+	}
+
+	// Key/value pairs must be written before sub-tables.
+	for _, d := range docs {
+		key := d.key(FormatTOML)
+		if key == "-" || d.isGroup {
+			continue
+		}
+		writeComments(d)
+		if value, ok := tomlValue(d.value); ok {
+			buf.WriteString(tomlKey(key) + " = " + value + "\n")
+		} else {
+			buf.WriteString("# " + tomlKey(key) + " =\n")
+		}
+	}
+	for _, d := range docs {
+		key := d.key(FormatTOML)
+		if key == "-" || !d.isGroup {
+			continue
+		}
+		subTable := append(table[:len(table):len(table)], tomlKey(key))
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		writeComments(d)
+		buf.WriteString("[" + strings.Join(subTable, ".") + "]\n")
+		writeTOMLSample(buf, d.children, subTable)
+	}
+}
+
+func tomlKey(key string) string {
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return tomlString(key)
+		}
+	}
+	return key
+}
+
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// tomlValue formats v as a TOML value, it returns false if v is nil
+// or v cannot be represented in TOML.
+func tomlValue(v reflect.Value) (string, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	switch x := v.Interface().(type) {
+	case time.Duration:
+		return tomlString(x.String()), true
+	case time.Time:
+		return x.Format(time.RFC3339Nano), true
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return tomlString(v.String()), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		switch {
+		case math.IsNaN(f):
+			return "nan", true
+		case math.IsInf(f, 1):
+			return "inf", true
+		case math.IsInf(f, -1):
+			return "-inf", true
+		}
+		s := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s, true
+	case reflect.Slice, reflect.Array:
+		elems := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elem, ok := tomlValue(v.Index(i))
+			if !ok {
+				return "", false
+			}
+			elems = append(elems, elem)
+		}
+		return "[" + strings.Join(elems, ", ") + "]", true
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]string, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			value, ok := tomlValue(iter.Value())
+			if !ok {
+				continue
+			}
+			key := fmt.Sprint(iter.Key().Interface())
+			keys = append(keys, key)
+			values[key] = value
+		}
+		sort.Strings(keys)
+		pairs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			pairs = append(pairs, tomlKey(key)+" = "+values[key])
+		}
+		return tomlInlineTable(pairs), true
+	case reflect.Struct:
+		var pairs []string
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			key := (&fieldDoc{field: field}).key(FormatTOML)
+			if key == "-" {
+				continue
+			}
+			if value, ok := tomlValue(v.Field(i)); ok {
+				pairs = append(pairs, tomlKey(key)+" = "+value)
+			}
+		}
+		return tomlInlineTable(pairs), true
+	}
+	return "", false
+}
+
+func tomlInlineTable(pairs []string) string {
+	if len(pairs) == 0 {
+		return "{}"
+	}
+	return "{ " + strings.Join(pairs, ", ") + " }"
+}
+
+// GenerateMarkdown writes a markdown table which documents fields of
+// config to w, config should be a struct or struct pointer.
+//
+// The table contains the field path, type, default value, environment
+// variable names, flag name, custom tag and description of each field.
+// Environment variable names are generated using the same rules as
+// Load, EnableImplicitEnv and EnvPrefix are respected.
+func (p *Loader) GenerateMarkdown(w io.Writer, config any) error {
+	docs, err := p.collectFieldDocs(config)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteString("| Field | Type | Default | Env | Flag | Custom | Description |\n")
+	buf.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+	var writeRows func(docs []*fieldDoc)
+	writeRows = func(docs []*fieldDoc) {
+		for _, d := range docs {
+			if !d.isGroup || len(d.envNames) > 0 || d.flagName != "" || d.customTag != "" || d.description != "" {
+				flagName := d.flagName
+				if flagName != "" {
+					flagName = "-" + flagName
+				}
+				cells := []string{
+					markdownCode(d.path),
+					markdownCode(d.field.Type.String()),
+					markdownCode(d.defaultVal),
+					markdownCode(strings.Join(d.envNames, ", ")),
+					markdownCode(flagName),
+					markdownCode(d.customTag),
+					markdownEscape(d.description),
+				}
+				buf.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+			}
+			writeRows(d.children)
+		}
+	}
+	writeRows(docs)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownEscape(s) + "`"
+}
+
+func markdownEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// RegisterFlags defines flags to fs for fields of config which have
+// a `flag` tag, config should be a struct or struct pointer.
+//
+// The flag's default value is taken from the `default` tag and the
+// usage message is taken from the `description` tag.
+// Flags for bool, integer, float, string and time.Duration fields
+// are defined with the corresponding types, flags for other fields
+// are defined as string flags.
+// After fs is parsed, set Config.FlagSet to fs to load the flag values.
+func (p *Loader) RegisterFlags(fs *flag.FlagSet, config any) error {
+	docs, err := p.collectFieldDocs(config)
+	if err != nil {
+		return err
+	}
+	return registerFlags(fs, docs, make(map[string]bool))
+}
+
+func registerFlags(fs *flag.FlagSet, docs []*fieldDoc, registered map[string]bool) error {
+	for _, d := range docs {
+		// Multiple fields may share a same flag.
+		if d.flagName != "" && !registered[d.flagName] {
+			if err := registerFlag(fs, d); err != nil {
+				return fmt.Errorf("cannot register flag for field %s: %w", d.path, err)
+			}
+			registered[d.flagName] = true
+		}
+		if err := registerFlags(fs, d.children, registered); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func registerFlag(fs *flag.FlagSet, d *fieldDoc) (err error) {
+	name, defVal, usage := d.flagName, d.defaultVal, d.description
+	if fs.Lookup(name) != nil {
+		return fmt.Errorf("flag redefined: %s", name)
+	}
+	typ := d.field.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == reflect.TypeOf(time.Duration(0)) {
+		var x time.Duration
+		if defVal != "" {
+			if x, err = cast.ToDurationE(defVal); err != nil {
+				return err
+			}
+		}
+		fs.Duration(name, x, usage)
+		return nil
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		var x bool
+		if x, err = toBooleanE(defVal); err != nil {
+			return err
+		}
+		fs.Bool(name, x, usage)
+	case reflect.Int:
+		var x int
+		if defVal != "" {
+			if x, err = cast.ToIntE(defVal); err != nil {
+				return err
+			}
+		}
+		fs.Int(name, x, usage)
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var x int64
+		if defVal != "" {
+			if x, err = cast.ToInt64E(defVal); err != nil {
+				return err
+			}
+		}
+		fs.Int64(name, x, usage)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var x uint64
+		if defVal != "" {
+			if x, err = cast.ToUint64E(defVal); err != nil {
+				return err
+			}
+		}
+		fs.Uint64(name, x, usage)
+	case reflect.Float32, reflect.Float64:
+		var x float64
+		if defVal != "" {
+			if x, err = cast.ToFloat64E(defVal); err != nil {
+				return err
+			}
+		}
+		fs.Float64(name, x, usage)
+	default:
+		fs.String(name, defVal, usage)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package confr
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type genDBConfig struct {
+	Addr    string        `json:"addr" toml:"addr" yaml:"addr" default:"127.0.0.1:3306" description:"Database address."`
+	Timeout time.Duration `json:"timeout" toml:"timeout" yaml:"timeout" default:"3s" flag:"db-timeout"`
+}
+
+type genConfig struct {
+	Name    string            `json:"name" toml:"name" yaml:"name" env:"APP_NAME" flag:"name" description:"Application name."`
+	Port    int               `json:"port" toml:"port" yaml:"port" default:"8080" flag:"port" description:"Port to listen on."`
+	Debug   bool              `json:"debug" toml:"debug" yaml:"debug" flag:"debug"`
+	Ratio   float64           `json:"ratio" toml:"ratio" yaml:"ratio" default:"1"`
+	Tags    []string          `json:"tags" toml:"tags" yaml:"tags"`
+	Labels  map[string]string `json:"labels" toml:"labels" yaml:"labels"`
+	Token   string            `custom:"app_token" json:"-" toml:"-" yaml:"-"`
+	DB      genDBConfig       `json:"db" toml:"db" yaml:"db"`
+	Replica *genDBConfig      `json:"replica" toml:"replica" yaml:"replica" description:"Optional read replica."`
+	Ignored string            `confr:"-"`
+}
+
+func TestGenerateSample(t *testing.T) {
+	sample := &genConfig{
+		Name:   "my-app",
+		Tags:   []string{"a", "b"},
+		Labels: map[string]string{"team": "infra"},
+	}
+	for _, format := range []string{FormatJSON, FormatTOML, FormatYAML} {
+		var buf bytes.Buffer
+		err := New(nil).GenerateSample(&buf, sample, format)
+		assert.Nil(t, err)
+		t.Logf("sample %s:\n%s", format, buf.String())
+
+		if format != FormatJSON {
+			assert.Contains(t, buf.String(), "# Application name.")
+			assert.Contains(t, buf.String(), "# Env: APP_NAME")
+			assert.Contains(t, buf.String(), "# Flag: -port")
+		}
+
+		fsys := fstest.MapFS{"app." + format: &fstest.MapFile{Data: buf.Bytes()}}
+		cfg := &genConfig{}
+		err = New(&Config{DisallowUnknownFields: true}).loadSources(cfg, NewFSSource(fsys, "app."+format))
+		assert.Nil(t, err)
+		assert.Equal(t, "my-app", cfg.Name)
+		assert.Equal(t, 8080, cfg.Port)
+		assert.Equal(t, 1.0, cfg.Ratio)
+		assert.Equal(t, []string{"a", "b"}, cfg.Tags)
+		assert.Equal(t, map[string]string{"team": "infra"}, cfg.Labels)
+		assert.Equal(t, "127.0.0.1:3306", cfg.DB.Addr)
+		assert.Equal(t, 3*time.Second, cfg.DB.Timeout)
+		assert.NotNil(t, cfg.Replica)
+		assert.Equal(t, "127.0.0.1:3306", cfg.Replica.Addr)
+	}
+
+	err := New(nil).GenerateSample(&bytes.Buffer{}, sample, "ini")
+	assert.NotNil(t, err)
+}
+
+func TestGenerateMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	loader := New(&Config{EnableImplicitEnv: true, EnvPrefix: "MyApp"})
+	err := loader.GenerateMarkdown(&buf, genConfig{})
+	assert.Nil(t, err)
+	t.Logf("markdown:\n%s", buf.String())
+
+	got := buf.String()
+	assert.Contains(t, got, "| Field | Type | Default | Env | Flag | Custom | Description |")
+	assert.Contains(t, got, "| `Name` | `string` |  | `APP_NAME` | `-name` |  | Application name. |")
+	assert.Contains(t, got, "| `Port` | `int` | `8080` | `MyApp_Port, MYAPP_PORT` | `-port` |  | Port to listen on. |")
+	assert.Contains(t, got, "| `DB.Timeout` | `time.Duration` | `3s` | `MyApp_DB_Timeout, MYAPP_DB_TIMEOUT` | `-db-timeout` |  |  |")
+	assert.Contains(t, got, "| `Token` | `string` |  | `MyApp_Token, MYAPP_TOKEN` |  | `app_token` |  |")
+	assert.Contains(t, got, "| `Replica` | `*confr.genDBConfig` |")
+	assert.NotContains(t, got, "| `DB` |")
+	assert.NotContains(t, got, "Ignored")
+}
+
+func TestRegisterFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := New(&Config{FlagSet: fs})
+	err := loader.RegisterFlags(fs, &genConfig{})
+	assert.Nil(t, err)
+
+	portFlag := fs.Lookup("port")
+	assert.NotNil(t, portFlag)
+	assert.Equal(t, "8080", portFlag.DefValue)
+	assert.Equal(t, "Port to listen on.", portFlag.Usage)
+
+	err = fs.Parse([]string{"-name", "flag-app", "-debug", "-db-timeout", "5s"})
+	assert.Nil(t, err)
+
+	cfg := &genConfig{}
+	err = loader.LoadSources(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "flag-app", cfg.Name)
+	assert.Equal(t, 8080, cfg.Port)
+	assert.True(t, cfg.Debug)
+	assert.Equal(t, 5*time.Second, cfg.DB.Timeout)
+
+	err = loader.RegisterFlags(fs, &genConfig{})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "flag redefined")
+}
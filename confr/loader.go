@@ -11,26 +11,39 @@
 // 3. Load from environment variables by explicitly defined `env` tag or
 // auto-generated names implicitly;
 //
-// 4. Load from multiple configuration fields with priority and overriding;
+// 4. Load from multiple configuration files or sources (e.g. [embed.FS],
+// in-memory map, remote HTTP URL) with priority and overriding,
+// and reload when sources change, see Loader.WatchSources;
 //
 // 5. Set default values by field tag `default` if a configuration field
 // is not given by any of the higher priority source;
 //
-// 6. Minimal dependency;
+// 6. Profile overlays (e.g. "app.yaml" + "app.prod.yaml") and per field
+// merge strategies defined by field tag `merge`;
+//
+// 7. Generate sample configuration files, markdown documentation and
+// command line flags from struct tags, see Loader.GenerateSample,
+// Loader.GenerateMarkdown and Loader.RegisterFlags;
+//
+// 8. Resolve secret references (e.g. "secret://name", "enc:...") in
+// configuration values by pluggable resolvers, see Config.SecretResolvers;
+//
+// 9. Minimal dependency;
 //
 // You may check Config and Loader for more details.
 package confr
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"path"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
@@ -43,12 +56,17 @@ import (
 
 const DefaultEnvPrefix = "Confr"
 
+// DefaultProfileEnv is the default environment variable to check
+// the active profile.
+const DefaultProfileEnv = "CONFR_PROFILE"
+
 const (
 	ConfrTag        = "confr"
 	CustomTag       = "custom"
 	DefaultValueTag = "default"
 	EnvTag          = "env"
 	FlagTag         = "flag"
+	MergeTag        = "merge"
 )
 
 // Config provides options to configure the behavior of Loader.
@@ -87,6 +105,29 @@ type Config struct {
 	// for fields which have a `flag` tag. The tag value should be the
 	// flag name to lookup for.
 	FlagSet *flag.FlagSet
+
+	// Profile optionally specifies the active profile, e.g. "staging",
+	// "prod". If Profile is empty, the loader checks the environment
+	// variable specified by ProfileEnv.
+	//
+	// When a profile is active, for each configuration file "base.yaml",
+	// the loader also loads "base.{profile}.yaml" if it exists, the
+	// profile file takes higher priority than the base file.
+	// This also works for sources created by NewFileSource and NewFSSource.
+	Profile string
+
+	// ProfileEnv specifies the environment variable to check the
+	// active profile. The default value is "CONFR_PROFILE".
+	ProfileEnv string
+
+	// SecretResolvers optionally specifies resolvers to resolve secret
+	// references in string values, the map key is the prefix of values
+	// which the resolver handles, e.g. "secret://", "enc:".
+	// Secrets are resolved after all other sources are processed,
+	// the resolved secret values are never written to logs.
+	//
+	// See NewGCMSecretResolver for a built-in resolver.
+	SecretResolvers map[string]SecretResolver
 }
 
 // Loader is used to load configuration from files (JSON/TOML/YAML),
@@ -100,11 +141,16 @@ type Config struct {
 //
 // 3. environment variables;
 //
-// 4. config files, if multiple files are given to Load, files appeared
-// first takes higher priority, if a config field appears in more
-// than one files, only the first has effect.
+// 4. config files or sources, if multiple files are given to Load
+// (or sources are given to LoadSources), files appeared first takes
+// higher priority, if a config field appears in more than one files,
+// only the first has effect.
 //
 // 5. default values defined by field tag `default`;
+//
+// When loading multiple files or sources, fields with a `merge` tag
+// are merged from all sources using the specified strategy,
+// see MergeReplace, MergeAppend and MergeByKey for details.
 type Loader struct {
 	*Config
 }
@@ -129,16 +175,68 @@ func Load(dst any, files ...string) error {
 //
 // See Loader and Config for detailed document.
 func (p *Loader) Load(dst any, files ...string) error {
-	return p.load(dst, files...)
+	return p.load(dst, fileSources(files)...)
+}
+
+// LoadSources loads configuration to dst using the Loader's Config
+// and the given configuration sources.
+// Sources take the same priority as configuration files, a source
+// appeared first takes higher priority.
+//
+// See Loader, Config and Source for detailed document.
+func (p *Loader) LoadSources(dst any, sources ...Source) error {
+	return p.load(dst, sources...)
+}
+
+// WatchSources loads configuration to dst like LoadSources, then it
+// watches the sources which implement Watcher in background until ctx
+// is done.
+//
+// Each time a source changes, configuration is reloaded from all
+// sources to a new value of the same type as dst, and onChange is
+// called with the new value, or with the error if reloading failed.
+// dst is never modified after WatchSources returns, thus it is safe
+// to be read concurrently. Calls to onChange are serialized.
+//
+// If the initial loading fails, it returns the error and does not
+// watch the sources.
+func (p *Loader) WatchSources(ctx context.Context, dst any, onChange func(cfg any, err error), sources ...Source) error {
+	if err := p.load(dst, sources...); err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	reload := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		cfg := reflect.New(reflect.TypeOf(dst).Elem()).Interface()
+		if err := p.load(cfg, sources...); err != nil {
+			onChange(nil, err)
+			return
+		}
+		onChange(cfg, nil)
+	}
+	for _, src := range p.addProfileSources(sources) {
+		w, ok := src.(Watcher)
+		if !ok {
+			continue
+		}
+		go func(src Source, w Watcher) {
+			if err := w.Watch(ctx, reload); err != nil {
+				p.getLogFunc()("failed to watch configuration source %v: %v", src.Name(), err)
+			}
+		}(src, w)
+	}
+	return nil
 }
 
-func (p *Loader) load(dst any, files ...string) error {
+func (p *Loader) load(dst any, sources ...Source) error {
 	dstTyp := reflect.TypeOf(dst)
 	if dstTyp.Kind() != reflect.Ptr || dstTyp.Elem().Kind() != reflect.Struct {
 		return errors.New("invalid destination, should be a struct pointer")
 	}
 
-	if err := p.loadFiles(dst, files...); err != nil {
+	if err := p.loadSources(dst, sources...); err != nil {
 		return err
 	}
 	if err := p.processEnv(dst, ""); err != nil {
@@ -153,6 +251,9 @@ func (p *Loader) load(dst any, files ...string) error {
 	if err := p.processFlags(dst); err != nil {
 		return err
 	}
+	if err := p.resolveSecrets(dst); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -164,9 +265,26 @@ func (p *Loader) getLogFunc() func(format string, v ...any) {
 }
 
 func (p *Loader) loadFiles(config any, files ...string) error {
-	for i := len(files) - 1; i >= 0; i-- {
-		file := files[i]
-		err := p.processFile(config, file)
+	return p.loadSources(config, fileSources(files)...)
+}
+
+func fileSources(files []string) []Source {
+	sources := make([]Source, 0, len(files))
+	for _, file := range files {
+		sources = append(sources, NewFileSource(file))
+	}
+	return sources
+}
+
+func (p *Loader) loadSources(config any, sources ...Source) error {
+	sources = p.addProfileSources(sources)
+	for i := len(sources) - 1; i >= 0; i-- {
+		mergeFields, err := detachMergeFields(config)
+		if err != nil {
+			return err
+		}
+		err = p.processSource(config, sources[i])
+		attachMergeFields(config, mergeFields)
 		if err != nil {
 			return err
 		}
@@ -174,33 +292,60 @@ func (p *Loader) loadFiles(config any, files ...string) error {
 	return nil
 }
 
-func (p *Loader) processFile(config any, file string) error {
-	if info, err := os.Stat(file); err != nil || !info.Mode().IsRegular() {
-		return fmt.Errorf("invalid configuration file: %s", file)
+func (p *Loader) getProfile() string {
+	if p.Profile != "" {
+		return p.Profile
 	}
+	envName := p.ProfileEnv
+	if envName == "" {
+		envName = DefaultProfileEnv
+	}
+	return os.Getenv(envName)
+}
 
-	p.getLogFunc()("loading configuration from file: %v", file)
+// addProfileSources inserts profile sources before the corresponding
+// base sources, thus profile sources take higher priority.
+func (p *Loader) addProfileSources(sources []Source) []Source {
+	profile := p.getProfile()
+	if profile == "" {
+		return sources
+	}
+	out := make([]Source, 0, 2*len(sources))
+	for _, src := range sources {
+		if ps, ok := src.(profileSource); ok {
+			if profileSrc := ps.withProfile(profile); profileSrc != nil {
+				out = append(out, profileSrc)
+			} else if p.Verbose {
+				p.getLogFunc()("profile %q not found for %v", profile, src.Name())
+			}
+		}
+		out = append(out, src)
+	}
+	return out
+}
+
+func (p *Loader) processSource(config any, src Source) error {
+	p.getLogFunc()("loading configuration from %v", src.Name())
+	data, format, err := src.Read()
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %w", src.Name(), err)
+	}
 	var unmarshalFunc = p.UnmarshalFunc
 	if unmarshalFunc == nil {
-		extname := path.Ext(file)
-		switch strings.ToLower(extname) {
-		case ".json":
+		switch format {
+		case FormatJSON:
 			unmarshalFunc = unmarshalJSON
-		case ".yaml", ".yml":
+		case FormatYAML:
 			unmarshalFunc = unmarshalYAML
-		case ".toml":
+		case FormatTOML:
 			unmarshalFunc = unmarshalTOML
 		default:
-			return fmt.Errorf("unsupported file type: %v", extname)
+			return fmt.Errorf("unsupported configuration format: %q, source: %s", format, src.Name())
 		}
 	}
-	data, err := os.ReadFile(file)
-	if err != nil {
-		return fmt.Errorf("cannot read file %s: %w", file, err)
-	}
 	err = unmarshalFunc(data, config, p.DisallowUnknownFields)
 	if err != nil {
-		return fmt.Errorf("cannot unmarshal file %s: %w", file, err)
+		return fmt.Errorf("cannot unmarshal %s: %w", src.Name(), err)
 	}
 	return nil
 }
@@ -363,7 +508,9 @@ func (p *Loader) processEnv(config any, prefix string) error {
 					envNames = append(envNames, name)
 				}
 			}
-		} else if p.EnableImplicitEnv {
+		} else if p.EnableImplicitEnv && !isGroupType(field.Type) {
+			// Group fields are not assignable from env, their
+			// sub-fields are processed recursively.
 			tmp := p.getEnvName(prefix, field.Name)
 			envNames = append(envNames, tmp, strings.ToUpper(tmp))
 		}
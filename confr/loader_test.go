@@ -478,6 +478,22 @@ func TestLoad_ImplicitEnv(t *testing.T) {
 	assert.Equal(t, "implicit env var override", cfg.ImplicitEnvVarOverride)
 }
 
+func TestLoad_ImplicitEnvGroupField(t *testing.T) {
+	type groupConfig struct {
+		Group struct {
+			Name string
+		}
+	}
+	t.Setenv("Confr_Group", "ignored")
+	t.Setenv("Confr_Group_Name", "group name")
+
+	cfg := &groupConfig{}
+	loader := New(&Config{EnableImplicitEnv: true})
+	err := loader.processEnv(cfg, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "group name", cfg.Group.Name)
+}
+
 func TestLoad_AllowUnknownFields_JSON(t *testing.T) {
 	configFiles := []string{"./testdata/config.unknown_fields.json"}
 	testLoad_AllowUnknownFields(t, configFiles...)
@@ -0,0 +1,207 @@
+package confr
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Merge strategies which can be specified by the field tag `merge`.
+//
+// Without a `merge` tag, a field is unmarshalled in place one source
+// after another, for slice fields it means the value is replaced by
+// the source with the highest priority, for map fields, the behavior
+// depends on the unmarshal function.
+const (
+	// MergeReplace replaces the field value as a whole by the
+	// source with the highest priority which contains the field.
+	MergeReplace = "replace"
+
+	// MergeAppend appends elements of a slice field from all sources,
+	// elements from a source with lower priority come first.
+	MergeAppend = "append"
+
+	// MergeByKey merges entries of a map field from all sources,
+	// the entry from the source with higher priority wins.
+	//
+	// For a slice field whose element is a struct or a pointer to
+	// struct, a key field must be specified in the form "key=FieldName",
+	// elements having the same key are replaced by the source with
+	// higher priority, other elements are appended.
+	MergeByKey = "key"
+)
+
+type mergeField struct {
+	path     []int
+	strategy string
+	keyField string
+	value    reflect.Value
+}
+
+// detachMergeFields saves and resets fields which have a `merge` tag,
+// thus after unmarshalling a source, we know exactly what the source
+// provides for the fields.
+func detachMergeFields(config any) ([]*mergeField, error) {
+	configVal := reflect.Indirect(reflect.ValueOf(config))
+	return walkMergeFields(configVal, nil, nil)
+}
+
+func walkMergeFields(configVal reflect.Value, path []int, out []*mergeField) ([]*mergeField, error) {
+	configTyp := configVal.Type()
+	for i := 0; i < configTyp.NumField(); i++ {
+		field := configTyp.Field(i)
+		fieldVal := configVal.Field(i)
+		if !fieldVal.CanAddr() || !fieldVal.CanInterface() {
+			continue
+		}
+		if field.Tag.Get(ConfrTag) == "-" {
+			continue
+		}
+
+		fieldPath := append(path[:len(path):len(path)], i)
+		mergeTag := field.Tag.Get(MergeTag)
+		if mergeTag != "" && mergeTag != "-" {
+			strategy, keyField, err := parseMergeTag(field.Type, mergeTag)
+			if err != nil {
+				return nil, fmt.Errorf("invalid merge tag for field %s.%s: %w", configTyp.Name(), field.Name, err)
+			}
+			saved := reflect.New(field.Type).Elem()
+			saved.Set(fieldVal)
+			fieldVal.Set(reflect.Zero(field.Type))
+			out = append(out, &mergeField{
+				path:     fieldPath,
+				strategy: strategy,
+				keyField: keyField,
+				value:    saved,
+			})
+			continue
+		}
+
+		fieldVal = reflect.Indirect(fieldVal)
+		if fieldVal.Kind() == reflect.Struct {
+			var err error
+			out, err = walkMergeFields(fieldVal, fieldPath, out)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+func parseMergeTag(typ reflect.Type, tag string) (strategy, keyField string, err error) {
+	strategy, keyField, _ = strings.Cut(tag, "=")
+	strategy = strings.TrimSpace(strategy)
+	keyField = strings.TrimSpace(keyField)
+	switch strategy {
+	case MergeReplace:
+		return strategy, "", nil
+	case MergeAppend:
+		if typ.Kind() != reflect.Slice {
+			return "", "", fmt.Errorf("%q requires a slice field", tag)
+		}
+		return strategy, "", nil
+	case MergeByKey:
+		switch typ.Kind() {
+		case reflect.Map:
+			return strategy, "", nil
+		case reflect.Slice:
+			elemTyp := typ.Elem()
+			if elemTyp.Kind() == reflect.Ptr {
+				elemTyp = elemTyp.Elem()
+			}
+			if elemTyp.Kind() != reflect.Struct || keyField == "" {
+				return "", "", fmt.Errorf("%q requires a slice of struct and a key field", tag)
+			}
+			if _, ok := elemTyp.FieldByName(keyField); !ok {
+				return "", "", fmt.Errorf("key field %q not found", keyField)
+			}
+			return strategy, keyField, nil
+		}
+		return "", "", fmt.Errorf("%q requires a map or slice field", tag)
+	}
+	return "", "", fmt.Errorf("unknown merge strategy %q", tag)
+}
+
+// attachMergeFields merges values provided by a source into the
+// saved values and sets the result back to config.
+func attachMergeFields(config any, fields []*mergeField) {
+	configVal := reflect.Indirect(reflect.ValueOf(config))
+	for _, mf := range fields {
+		fieldVal := fieldByPath(configVal, mf.path)
+		fieldVal.Set(mergeValue(mf, mf.value, fieldVal))
+	}
+}
+
+func fieldByPath(v reflect.Value, path []int) reflect.Value {
+	for _, idx := range path {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	return v
+}
+
+func mergeValue(mf *mergeField, old, new reflect.Value) reflect.Value {
+	if new.IsZero() {
+		return old
+	}
+	if old.IsZero() {
+		return new
+	}
+	switch mf.strategy {
+	case MergeAppend:
+		out := reflect.MakeSlice(old.Type(), 0, old.Len()+new.Len())
+		out = reflect.AppendSlice(out, old)
+		return reflect.AppendSlice(out, new)
+	case MergeByKey:
+		if old.Kind() == reflect.Map {
+			out := reflect.MakeMapWithSize(old.Type(), old.Len()+new.Len())
+			for _, m := range []reflect.Value{old, new} {
+				iter := m.MapRange()
+				for iter.Next() {
+					out.SetMapIndex(iter.Key(), iter.Value())
+				}
+			}
+			return out
+		}
+		out := reflect.MakeSlice(old.Type(), 0, old.Len()+new.Len())
+		out = reflect.AppendSlice(out, old)
+		index := make(map[any]int, old.Len())
+		for i := 0; i < old.Len(); i++ {
+			if key, ok := elemKey(old.Index(i), mf.keyField); ok {
+				index[key] = i
+			}
+		}
+		for i := 0; i < new.Len(); i++ {
+			elem := new.Index(i)
+			key, ok := elemKey(elem, mf.keyField)
+			if j, found := index[key]; ok && found {
+				out.Index(j).Set(elem)
+				continue
+			}
+			out = reflect.Append(out, elem)
+			if ok {
+				index[key] = out.Len() - 1
+			}
+		}
+		return out
+	}
+	return new
+}
+
+func elemKey(elem reflect.Value, keyField string) (any, bool) {
+	elem = reflect.Indirect(elem)
+	if !elem.IsValid() {
+		return nil, false
+	}
+	key := elem.FieldByName(keyField)
+	if !key.IsValid() || !key.CanInterface() || !key.Type().Comparable() {
+		return nil, false
+	}
+	return key.Interface(), true
+}
@@ -0,0 +1,121 @@
+package confr
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type profileServer struct {
+	Name string `yaml:"name"`
+	Addr string `yaml:"addr"`
+}
+
+type profileConfig struct {
+	Name    string            `yaml:"name"`
+	Level   string            `yaml:"level"`
+	Servers []*profileServer  `yaml:"servers" merge:"key=Name"`
+	Plugins []string          `yaml:"plugins" merge:"append"`
+	Labels  map[string]string `yaml:"labels" merge:"key"`
+	Hosts   []string          `yaml:"hosts" merge:"replace"`
+	DB      DBConfig          `yaml:"db"`
+}
+
+func TestLoad_Profile(t *testing.T) {
+	t.Run("no profile", func(t *testing.T) {
+		cfg := &profileConfig{}
+		err := New(nil).Load(cfg, "./testdata/profile/app.yaml")
+		assert.Nil(t, err)
+		assert.Equal(t, "debug", cfg.Level)
+		assert.Len(t, cfg.Servers, 2)
+		assert.Equal(t, []string{"auth", "metrics"}, cfg.Plugins)
+	})
+
+	t.Run("profile env", func(t *testing.T) {
+		os.Setenv("TEST_APP_PROFILE", "prod")
+		defer os.Unsetenv("TEST_APP_PROFILE")
+
+		cfg := &profileConfig{}
+		err := New(&Config{ProfileEnv: "TEST_APP_PROFILE"}).Load(cfg, "./testdata/profile/app.yaml")
+		assert.Nil(t, err)
+		assertProdProfileConfig(t, cfg)
+	})
+
+	t.Run("fs source", func(t *testing.T) {
+		cfg := &profileConfig{}
+		err := New(&Config{Profile: "prod"}).LoadSources(cfg, NewFSSource(testdataFS, "testdata/profile/app.yaml"))
+		assert.Nil(t, err)
+		assertProdProfileConfig(t, cfg)
+	})
+
+	t.Run("profile not exist", func(t *testing.T) {
+		cfg := &profileConfig{}
+		err := New(&Config{Profile: "staging", Verbose: true}).Load(cfg, "./testdata/profile/app.yaml")
+		assert.Nil(t, err)
+		assert.Equal(t, "debug", cfg.Level)
+	})
+}
+
+func assertProdProfileConfig(t *testing.T, cfg *profileConfig) {
+	assert.Equal(t, "app", cfg.Name)
+	assert.Equal(t, "info", cfg.Level)
+	assert.Equal(t, []*profileServer{
+		{Name: "s1", Addr: "10.0.0.1:80"},
+		{Name: "s2", Addr: "192.168.0.2:80"},
+		{Name: "s3", Addr: "192.168.0.3:80"},
+	}, cfg.Servers)
+	assert.Equal(t, []string{"auth", "metrics", "tracing"}, cfg.Plugins)
+	assert.Equal(t, map[string]string{"team": "infra", "env": "prod"}, cfg.Labels)
+	assert.Equal(t, []string{"prod.example.com"}, cfg.Hosts)
+	assert.Equal(t, "prod_mysql_dsn", cfg.DB.MySQL)
+	assert.Equal(t, "base_redis_dsn", cfg.DB.Redis)
+}
+
+func TestLoad_MergeStrategies(t *testing.T) {
+	type nested struct {
+		Tags []string `json:"tags" merge:"append"`
+	}
+	type mergeConfig struct {
+		Tags   []string       `json:"tags" merge:"append"`
+		Limits map[string]int `json:"limits" merge:"key"`
+		Nested *nested        `json:"nested"`
+	}
+
+	cfg := &mergeConfig{}
+	err := New(nil).LoadSources(cfg,
+		NewMapSource("high", map[string]any{
+			"tags":   []string{"c"},
+			"limits": map[string]int{"b": 20, "c": 30},
+			"nested": map[string]any{"tags": []string{"y"}},
+		}),
+		NewMapSource("middle", map[string]any{
+			"limits": map[string]int{"a": 1},
+		}),
+		NewMapSource("low", map[string]any{
+			"tags":   []string{"a", "b"},
+			"limits": map[string]int{"a": 10, "b": 10},
+			"nested": map[string]any{"tags": []string{"x"}},
+		}),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, cfg.Tags)
+	assert.Equal(t, map[string]int{"a": 1, "b": 20, "c": 30}, cfg.Limits)
+	assert.Equal(t, []string{"x", "y"}, cfg.Nested.Tags)
+}
+
+func TestLoad_InvalidMergeTag(t *testing.T) {
+	type invalidConfig struct {
+		Name string `json:"name" merge:"append"`
+	}
+	err := New(nil).LoadSources(&invalidConfig{}, NewMapSource("test", nil))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid merge tag")
+
+	type invalidKeyConfig struct {
+		Servers []profileServer `json:"servers" merge:"key=ID"`
+	}
+	err = New(nil).LoadSources(&invalidKeyConfig{}, NewMapSource("test", nil))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "key field")
+}
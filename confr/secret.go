@@ -0,0 +1,175 @@
+package confr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/jxskiss/gopkg/v2/encrypt/crypto"
+)
+
+// SecretResolver resolves a secret reference to the secret value.
+// ref is the configuration value with the prefix which the resolver
+// is registered with stripped, e.g. "db_password" for value
+// "secret://db_password" if the resolver is registered with prefix
+// "secret://".
+//
+// A SecretResolver should not include the secret value in the
+// returned error.
+type SecretResolver func(ref string) (string, error)
+
+// resolveSecrets replaces string values which start with prefixes
+// registered in Config.SecretResolvers by the resolved secret values.
+func (p *Loader) resolveSecrets(config any) error {
+	if len(p.SecretResolvers) == 0 {
+		return nil
+	}
+	configVal := reflect.Indirect(reflect.ValueOf(config))
+	return p.walkSecrets(configVal, configVal.Type().Name())
+}
+
+func (p *Loader) walkSecrets(val reflect.Value, path string) error {
+	switch val.Kind() {
+	case reflect.Ptr:
+		if val.IsNil() {
+			return nil
+		}
+		return p.walkSecrets(val.Elem(), path)
+	case reflect.String:
+		if !val.CanSet() {
+			return nil
+		}
+		secret, ok, err := p.resolveSecret(val.String(), path)
+		if err != nil {
+			return err
+		}
+		if ok {
+			val.SetString(secret)
+		}
+	case reflect.Struct:
+		typ := val.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() || field.Tag.Get(ConfrTag) == "-" {
+				continue
+			}
+			if err := p.walkSecrets(val.Field(i), path+"."+field.Name); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			if err := p.walkSecrets(val.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if val.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		iter := val.MapRange()
+		for iter.Next() {
+			elemPath := fmt.Sprintf("%s[%v]", path, iter.Key())
+			secret, ok, err := p.resolveSecret(iter.Value().String(), elemPath)
+			if err != nil {
+				return err
+			}
+			if ok {
+				val.SetMapIndex(iter.Key(), reflect.ValueOf(secret).Convert(val.Type().Elem()))
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Loader) resolveSecret(value string, path string) (string, bool, error) {
+	// The longest matching prefix wins.
+	var prefix string
+	for x := range p.SecretResolvers {
+		if x != "" && len(x) > len(prefix) && strings.HasPrefix(value, x) {
+			prefix = x
+		}
+	}
+	if prefix == "" {
+		return "", false, nil
+	}
+
+	// Never log the secret value.
+	if p.Verbose {
+		p.getLogFunc()("resolving secret for field %s with prefix %q", path, prefix)
+	}
+	secret, err := p.SecretResolvers[prefix](strings.TrimPrefix(value, prefix))
+	if err != nil {
+		return "", false, fmt.Errorf("cannot resolve secret for field %s: %w", path, err)
+	}
+	return secret, true, nil
+}
+
+// GCMSecretOptions configures the SecretResolver created by
+// NewGCMSecretResolver.
+type GCMSecretOptions struct {
+
+	// KeyEnv specifies an environment variable to read the key.
+	KeyEnv string
+
+	// KeyFile specifies a file to read the key, it is checked if KeyEnv
+	// is empty or the environment variable is not set.
+	// Leading and trailing white spaces of the file content are trimmed.
+	KeyFile string
+
+	// CryptoOptions optionally specifies options passed to
+	// crypto.GCMDecrypt. By default, it is crypto.Base64(nil),
+	// which means the ciphertext is encoded with standard base64 encoding.
+	CryptoOptions []crypto.Option
+}
+
+// NewGCMSecretResolver returns a SecretResolver which decrypts
+// ciphertext using crypto.GCMDecrypt.
+// The ciphertext is expected to be created by crypto.GCMEncrypt
+// with the same key and options.
+//
+// Example:
+//
+//	loader := confr.New(&confr.Config{
+//		SecretResolvers: map[string]confr.SecretResolver{
+//			"enc:": confr.NewGCMSecretResolver(confr.GCMSecretOptions{KeyEnv: "APP_SECRET_KEY"}),
+//		},
+//	})
+func NewGCMSecretResolver(opts GCMSecretOptions) SecretResolver {
+	cryptoOpts := opts.CryptoOptions
+	if len(cryptoOpts) == 0 {
+		cryptoOpts = []crypto.Option{crypto.Base64(nil)}
+	}
+	return func(ref string) (string, error) {
+		key, err := opts.readKey()
+		if err != nil {
+			return "", err
+		}
+		plaintext, err := crypto.GCMDecrypt([]byte(ref), key, cryptoOpts...)
+		if err != nil {
+			return "", fmt.Errorf("cannot decrypt secret: %w", err)
+		}
+		return string(plaintext), nil
+	}
+}
+
+func (opts GCMSecretOptions) readKey() ([]byte, error) {
+	if opts.KeyEnv != "" {
+		if key := os.Getenv(opts.KeyEnv); key != "" {
+			return []byte(key), nil
+		}
+	}
+	if opts.KeyFile != "" {
+		data, err := os.ReadFile(opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read secret key file: %w", err)
+		}
+		if key := bytes.TrimSpace(data); len(key) > 0 {
+			return key, nil
+		}
+	}
+	return nil, errors.New("secret key not found")
+}
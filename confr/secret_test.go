@@ -0,0 +1,109 @@
+package confr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jxskiss/gopkg/v2/encrypt/crypto"
+)
+
+type secretConfig struct {
+	DBPassword string            `json:"db_password" env:"TEST_SECRET_DB_PASSWORD"`
+	APIKey     *string           `json:"api_key"`
+	Tokens     []string          `json:"tokens"`
+	Headers    map[string]string `json:"headers"`
+	Plain      string            `json:"plain"`
+	Nested     struct {
+		Password string `json:"password"`
+	} `json:"nested"`
+}
+
+func TestLoad_SecretResolvers(t *testing.T) {
+	const key = "test-secret-key"
+	const password = "my-db-password"
+	ciphertext, err := crypto.GCMEncrypt([]byte(password), []byte(key), crypto.Base64(nil))
+	assert.Nil(t, err)
+
+	os.Setenv("TEST_SECRET_KEY", key)
+	defer os.Unsetenv("TEST_SECRET_KEY")
+	os.Setenv("TEST_SECRET_DB_PASSWORD", "enc:"+string(ciphertext))
+	defer os.Unsetenv("TEST_SECRET_DB_PASSWORD")
+
+	secrets := map[string]string{
+		"api_key":  "my-api-key",
+		"token_1":  "my-token-1",
+		"auth":     "my-auth-header",
+		"password": "my-nested-password",
+	}
+	var logs strings.Builder
+	loader := New(&Config{
+		Verbose: true,
+		LogFunc: func(format string, v ...any) {
+			fmt.Fprintf(&logs, format+"\n", v...)
+		},
+		SecretResolvers: map[string]SecretResolver{
+			"enc:": NewGCMSecretResolver(GCMSecretOptions{KeyEnv: "TEST_SECRET_KEY"}),
+			"secret://": func(ref string) (string, error) {
+				if s, ok := secrets[ref]; ok {
+					return s, nil
+				}
+				return "", errors.New("secret not found")
+			},
+		},
+	})
+
+	cfg := &secretConfig{}
+	err = loader.LoadSources(cfg, NewMapSource("test", map[string]any{
+		"api_key": "secret://api_key",
+		"tokens":  []string{"secret://token_1", "plain_token"},
+		"headers": map[string]string{"Authorization": "secret://auth"},
+		"plain":   "plain_value",
+		"nested":  map[string]any{"password": "secret://password"},
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, password, cfg.DBPassword)
+	assert.Equal(t, "my-api-key", *cfg.APIKey)
+	assert.Equal(t, []string{"my-token-1", "plain_token"}, cfg.Tokens)
+	assert.Equal(t, map[string]string{"Authorization": "my-auth-header"}, cfg.Headers)
+	assert.Equal(t, "plain_value", cfg.Plain)
+	assert.Equal(t, "my-nested-password", cfg.Nested.Password)
+
+	assert.Contains(t, logs.String(), "resolving secret for field secretConfig.DBPassword")
+	for _, secret := range []string{password, "my-api-key", "my-token-1", "my-auth-header", "my-nested-password"} {
+		assert.NotContains(t, logs.String(), secret)
+	}
+
+	err = loader.LoadSources(&secretConfig{}, NewMapSource("test", map[string]any{
+		"plain": "secret://not_exists",
+	}))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "secretConfig.Plain")
+}
+
+func TestGCMSecretResolver(t *testing.T) {
+	const key = "file-secret-key"
+	keyFile := filepath.Join(t.TempDir(), "secret.key")
+	err := os.WriteFile(keyFile, []byte(key+"\n"), 0o600)
+	assert.Nil(t, err)
+
+	ciphertext, err := crypto.GCMEncrypt([]byte("hello"), []byte(key), crypto.Base64(nil))
+	assert.Nil(t, err)
+
+	resolver := NewGCMSecretResolver(GCMSecretOptions{KeyEnv: "TEST_SECRET_KEY_NOT_SET", KeyFile: keyFile})
+	got, err := resolver(string(ciphertext))
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", got)
+
+	_, err = resolver("aGVsbG8=")
+	assert.ErrorIs(t, err, crypto.ErrCiphertextTooShort)
+
+	_, err = NewGCMSecretResolver(GCMSecretOptions{})(string(ciphertext))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "secret key not found")
+}
@@ -0,0 +1,306 @@
+package confr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Configuration formats supported by Loader.
+const (
+	FormatJSON = "json"
+	FormatTOML = "toml"
+	FormatYAML = "yaml"
+)
+
+// DefaultPollInterval is the default interval used by sources which
+// watch changes by polling.
+const DefaultPollInterval = 30 * time.Second
+
+// Source provides configuration data to Loader.
+//
+// Sources passed to Loader.LoadSources follow the same priority rule
+// as configuration files, a source appeared first takes higher priority.
+type Source interface {
+	// Name returns a human-readable name to identify the source
+	// in log messages and errors.
+	Name() string
+
+	// Read reads the configuration data and returns the data and format,
+	// the format should be one of FormatJSON, FormatTOML and FormatYAML.
+	// If Config.UnmarshalFunc is set, the format is ignored.
+	Read() (data []byte, format string, err error)
+}
+
+// Watcher is an optional interface which a Source may implement to
+// notify changes of the configuration data, see Loader.WatchSources.
+type Watcher interface {
+	// Watch blocks and calls onChange each time it detects a change of
+	// the source, until ctx is done or an unrecoverable error happens.
+	// It returns nil when ctx is done.
+	Watch(ctx context.Context, onChange func()) error
+}
+
+// profileSource is implemented by sources which support profile overlays.
+type profileSource interface {
+	// withProfile returns the source for the given profile,
+	// it returns nil if the profile source does not exist.
+	withProfile(profile string) Source
+}
+
+// NewFileSource returns a Source which reads configuration from a local
+// file, the format is determined by the file extension.
+//
+// The returned Source also implements Watcher, it checks the file's
+// modification time every DefaultPollInterval.
+func NewFileSource(file string) Source {
+	return &fileSource{file: file}
+}
+
+type fileSource struct {
+	file string
+}
+
+func (p *fileSource) Name() string { return "file " + p.file }
+
+func (p *fileSource) Read() ([]byte, string, error) {
+	if info, err := os.Stat(p.file); err != nil || !info.Mode().IsRegular() {
+		return nil, "", fmt.Errorf("invalid configuration file: %s", p.file)
+	}
+	data, err := os.ReadFile(p.file)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, formatFromExt(path.Ext(p.file)), nil
+}
+
+func (p *fileSource) withProfile(profile string) Source {
+	file := profileFileName(p.file, profile)
+	if info, err := os.Stat(file); err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	return NewFileSource(file)
+}
+
+func (p *fileSource) Watch(ctx context.Context, onChange func()) error {
+	var lastMod time.Time
+	if info, err := os.Stat(p.file); err == nil {
+		lastMod = info.ModTime()
+	}
+	return poll(ctx, DefaultPollInterval, func() {
+		info, err := os.Stat(p.file)
+		if err != nil {
+			return
+		}
+		if modTime := info.ModTime(); !modTime.Equal(lastMod) {
+			lastMod = modTime
+			onChange()
+		}
+	})
+}
+
+// NewFSSource returns a Source which reads configuration from file name
+// in fsys, fsys can be any [fs.FS] implementation, e.g. an [embed.FS].
+// The format is determined by the file extension.
+func NewFSSource(fsys fs.FS, name string) Source {
+	return &fsSource{fsys: fsys, name: name}
+}
+
+type fsSource struct {
+	fsys fs.FS
+	name string
+}
+
+func (p *fsSource) Name() string { return "fs " + p.name }
+
+func (p *fsSource) Read() ([]byte, string, error) {
+	data, err := fs.ReadFile(p.fsys, p.name)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, formatFromExt(path.Ext(p.name)), nil
+}
+
+func (p *fsSource) withProfile(profile string) Source {
+	name := profileFileName(p.name, profile)
+	if info, err := fs.Stat(p.fsys, name); err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	return NewFSSource(p.fsys, name)
+}
+
+// NewMapSource returns a Source which provides configuration from
+// an in-memory map, the map is encoded as JSON, thus keys of values
+// should match the `json` tags of the destination struct.
+// name is used to identify the source in log messages and errors.
+func NewMapSource(name string, values map[string]any) Source {
+	return &mapSource{name: name, values: values}
+}
+
+type mapSource struct {
+	name   string
+	values map[string]any
+}
+
+func (p *mapSource) Name() string { return "map " + p.name }
+
+func (p *mapSource) Read() ([]byte, string, error) {
+	data, err := json.Marshal(p.values)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, FormatJSON, nil
+}
+
+// HTTPSourceOptions customizes the behavior of a Source created by
+// NewHTTPSource.
+type HTTPSourceOptions struct {
+
+	// Client optionally specifies the http client to use,
+	// by default http.DefaultClient is used.
+	Client *http.Client
+
+	// Header optionally specifies extra headers to send with requests.
+	Header http.Header
+
+	// Format optionally specifies the format of the response body.
+	// By default, it is determined by the response Content-Type,
+	// or the extension of the URL path if Content-Type is not
+	// recognized.
+	Format string
+
+	// PollInterval specifies the interval to check changes when
+	// watching the source. The default is DefaultPollInterval.
+	PollInterval time.Duration
+}
+
+// NewHTTPSource returns a Source which reads configuration from
+// a remote HTTP URL using GET requests, opts may be nil.
+//
+// The returned Source also implements Watcher, it polls the URL
+// and compares the response body to detect changes.
+func NewHTTPSource(url string, opts *HTTPSourceOptions) Source {
+	if opts == nil {
+		opts = &HTTPSourceOptions{}
+	}
+	return &httpSource{url: url, opts: *opts}
+}
+
+type httpSource struct {
+	url  string
+	opts HTTPSourceOptions
+}
+
+func (p *httpSource) Name() string { return p.url }
+
+func (p *httpSource) Read() ([]byte, string, error) {
+	return p.read(context.Background())
+}
+
+func (p *httpSource) read(ctx context.Context) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	for k, v := range p.opts.Header {
+		req.Header[k] = v
+	}
+	client := p.opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	format := p.opts.Format
+	if format == "" {
+		format = formatFromContentType(resp.Header.Get("Content-Type"))
+	}
+	if format == "" {
+		if u, err := url.Parse(p.url); err == nil {
+			format = formatFromExt(path.Ext(u.Path))
+		}
+	}
+	return data, format, nil
+}
+
+func (p *httpSource) Watch(ctx context.Context, onChange func()) error {
+	interval := p.opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	last, _, _ := p.read(ctx)
+	return poll(ctx, interval, func() {
+		data, _, err := p.read(ctx)
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(data, last) {
+			last = data
+			onChange()
+		}
+	})
+}
+
+func poll(ctx context.Context, interval time.Duration, check func()) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			check()
+		}
+	}
+}
+
+// profileFileName returns "base.{profile}.yaml" for "base.yaml".
+func profileFileName(name, profile string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + profile + ext
+}
+
+func formatFromExt(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return strings.TrimPrefix(ext, ".")
+}
+
+func formatFromContentType(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json":
+		return FormatJSON
+	case "application/toml":
+		return FormatTOML
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return FormatYAML
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package confr
+
+import (
+	"context"
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//go:embed testdata
+var testdataFS embed.FS
+
+func TestLoadSources_EmbedFS(t *testing.T) {
+	for _, ext := range []string{"json", "toml", "yml"} {
+		cfg := &TestConfig{}
+		loader := New(&Config{Verbose: true})
+		err := loader.loadSources(cfg,
+			NewFSSource(testdataFS, "testdata/config.test."+ext),
+			NewFSSource(testdataFS, "testdata/config.common."+ext),
+		)
+		assert.Nil(t, err)
+		assertSingleFileConfig(t, cfg)
+		assertCommonConfig(t, cfg)
+	}
+}
+
+func TestLoadSources_MapFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.yaml": &fstest.MapFile{Data: []byte("some_1: from_map_fs\nsome_2: 123\n")},
+	}
+	cfg := &TestConfig{}
+	err := New(nil).LoadSources(cfg, NewFSSource(fsys, "app.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, "from_map_fs", cfg.Some1)
+	assert.Equal(t, 123, cfg.Some2)
+	assert.Equal(t, 2*time.Second, cfg.SomeDuration)
+
+	err = New(nil).LoadSources(cfg, NewFSSource(fsys, "not_exists.yaml"))
+	assert.NotNil(t, err)
+}
+
+func TestLoadSources_MapSource(t *testing.T) {
+	cfg := &TestConfig{}
+	err := New(nil).LoadSources(cfg,
+		NewMapSource("override", map[string]any{
+			"some_1": "from_map",
+			"db_ptr": map[string]any{"mysql": "override_mysql_dsn"},
+		}),
+		NewFileSource("./testdata/config.test.yml"),
+	)
+	assert.Nil(t, err)
+	assertSingleFileConfig(t, cfg, "some_1", "mysql_dsn")
+	assert.Equal(t, "from_map", cfg.Some1)
+	assert.Equal(t, "override_mysql_dsn", cfg.DBPtr.MySQL)
+	assert.Equal(t, "redis_dsn", cfg.DBPtr.Redis)
+}
+
+func TestLoadSources_HTTPSource(t *testing.T) {
+	data, err := os.ReadFile("./testdata/config.test.toml")
+	assert.Nil(t, err)
+	var version atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/config.toml":
+			w.Write(data)
+		case "/config":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"some_1": "remote_some_1"}`))
+		case "/version":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"some_2": ` + strconv.Itoa(int(version.Load())) + `}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := &TestConfig{}
+	err = New(nil).loadSources(cfg,
+		NewHTTPSource(srv.URL+"/config", nil),
+		NewHTTPSource(srv.URL+"/config.toml", nil),
+		NewFSSource(testdataFS, "testdata/config.common.yml"),
+	)
+	assert.Nil(t, err)
+	assertSingleFileConfig(t, cfg, "some_1")
+	assertCommonConfig(t, cfg)
+	assert.Equal(t, "remote_some_1", cfg.Some1)
+
+	err = New(nil).LoadSources(cfg, NewHTTPSource(srv.URL+"/not_found.json", nil))
+	assert.NotNil(t, err)
+
+	src := NewHTTPSource(srv.URL+"/version", &HTTPSourceOptions{PollInterval: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	changed := make(chan struct{}, 1)
+	go src.(Watcher).Watch(ctx, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	time.Sleep(30 * time.Millisecond)
+	version.Store(1)
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("change not detected")
+	}
+	cancel()
+
+	cfg = &TestConfig{}
+	err = New(nil).LoadSources(cfg, src)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cfg.Some2)
+}
+
+func TestWatchSources(t *testing.T) {
+	var version atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"some_2": ` + strconv.Itoa(int(version.Load())) + `}`))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan *TestConfig, 1)
+	cfg := &TestConfig{}
+	err := New(nil).WatchSources(ctx, cfg, func(newCfg any, err error) {
+		assert.Nil(t, err)
+		changed <- newCfg.(*TestConfig)
+	},
+		NewHTTPSource(srv.URL+"/version", &HTTPSourceOptions{PollInterval: 10 * time.Millisecond}),
+		NewMapSource("defaults", map[string]any{"some_1": "from_map"}),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, cfg.Some2)
+
+	time.Sleep(30 * time.Millisecond)
+	version.Store(1)
+	select {
+	case newCfg := <-changed:
+		assert.Equal(t, 1, newCfg.Some2)
+		assert.Equal(t, "from_map", newCfg.Some1)
+		assert.Equal(t, 0, cfg.Some2)
+	case <-time.After(time.Second):
+		t.Fatal("change not detected")
+	}
+
+	err = New(nil).WatchSources(ctx, &TestConfig{}, func(any, error) {
+		t.Error("unexpected change")
+	}, NewFSSource(testdataFS, "testdata/not_exist.json"))
+	assert.NotNil(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"io"
 )
 
@@ -14,6 +15,10 @@ const (
 	gcmStandardNonceSize = 12 // crypto/cipher.gcmStandardNonceSize
 )
 
+// ErrCiphertextTooShort is returned when the ciphertext is shorter
+// than required by the cipher mode.
+var ErrCiphertextTooShort = errors.New("crypto: ciphertext too short")
+
 // GCMEncrypt encrypts plaintext with key using the GCM mode.
 // The returned ciphertext contains the nonce, encrypted text and
 // the additional data authentication tag. If additional data is not
@@ -115,6 +120,9 @@ func GCMDecrypt(ciphertext, key []byte, opts ...Option) (plaintext []byte, err e
 	if err != nil {
 		return nil, err
 	}
+	if len(ciphertext) < nonceSize+gcmTagSize {
+		return nil, ErrCiphertextTooShort
+	}
 	nonce := ciphertext[:nonceSize]
 	ciphertext = ciphertext[nonceSize:]
 	block, err := aes.NewCipher(key)
@@ -26,6 +26,13 @@ func Test_GCM(t *testing.T) {
 	}
 }
 
+func Test_GCM_ShortCiphertext(t *testing.T) {
+	for _, ciphertext := range [][]byte{nil, []byte("short"), make([]byte, gcmStandardNonceSize+gcmTagSize-1)} {
+		_, err := GCMDecrypt(ciphertext, testKeyList[0])
+		assert.Equal(t, ErrCiphertextTooShort, err)
+	}
+}
+
 func Test_GCM_EmptyKey(t *testing.T) {
 	emptyKey := []byte("")
 	ciphertext, err := GCMEncrypt(plaintext, emptyKey)
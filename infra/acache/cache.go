@@ -3,30 +3,32 @@ package acache
 import (
 	"errors"
 	"fmt"
-	"sync"
-	"sync/atomic"
 	"time"
-	"unsafe"
-
-	"golang.org/x/sync/singleflight"
-
-	"github.com/jxskiss/gopkg/v2/collection/heapx"
-	"github.com/jxskiss/gopkg/v2/internal/functicker"
 )
 
 // ErrFetchTimeout indicates a timeout error when refresh a cached value
 // if Options.FetchTimeout is specified.
 var ErrFetchTimeout = errors.New("fetch timeout")
 
+// ErrNotFound can be returned (maybe wrapped) by a Fetcher to indicate
+// that a key does not exist in the upstream system.
+var ErrNotFound = errors.New("not found")
+
+// ErrStale indicates that a cached value exceeds Options.MaxStaleness,
+// the returned error may also wrap the last error returned by Fetcher.
+var ErrStale = errors.New("stale value")
+
 // Options configures the behavior of Cache.
+// See TypedOptions for the generic version.
 type Options struct {
 
 	// Fetcher fetches data from upstream system for a given key.
 	// Result value or error will be cached till next refresh execution.
+	// A Fetcher returns ErrNotFound (maybe wrapped) to indicate that
+	// the key does not exist in the upstream system.
 	//
-	// The provided Fetcher implementation must return consistently
-	// typed values, else it panics when storing a value of different
-	// type into the underlying sync/atomic.Value.
+	// If the Fetcher implements BatchFetcher, BatchFetch is used to
+	// refresh the cached values in batch.
 	//
 	// The returned value from this function should not be changed after
 	// retrieved from the Cache, else data race happens since there may be
@@ -64,6 +66,24 @@ type Options struct {
 	// prevents it being deleted from the cache.
 	ExpireInterval time.Duration
 
+	// MaxStaleness optionally limits how long a cached value can be
+	// served since it was last fetched successfully.
+	// See TypedOptions.MaxStaleness for details.
+	MaxStaleness time.Duration
+
+	// BlockOnStale tells Get to block and fetch a fresh value from
+	// upstream when the cached value exceeds MaxStaleness.
+	// See TypedOptions.BlockOnStale for details.
+	BlockOnStale bool
+
+	// ErrorTTL optionally specifies how long an error returned by
+	// Fetcher is cached. See TypedOptions.ErrorTTL for details.
+	ErrorTTL time.Duration
+
+	// NotFoundTTL optionally specifies how long a not found result is
+	// cached. See TypedOptions.NotFoundTTL for details.
+	NotFoundTTL time.Duration
+
 	// ErrorCallback is an optional callback which will be called when
 	// an error is returned by Fetcher during refresh.
 	ErrorCallback func(err error, keys []string)
@@ -75,6 +95,40 @@ type Options struct {
 	// DeleteCallback is an optional callback which will be called when
 	// a value is deleted from the cache.
 	DeleteCallback func(key string, data any)
+
+	// Name optionally names the cache, it is reported in Stats.
+	Name string
+
+	// StatsExporter optionally receives statistics of the cache
+	// periodically, see StatsExportInterval.
+	StatsExporter StatsExporter
+
+	// StatsExportInterval specifies the interval to call StatsExporter,
+	// default is one minute.
+	StatsExportInterval time.Duration
+
+	// MaxEntries optionally limits the number of entries in the cache,
+	// default is zero which means no limit.
+	//
+	// When the cache exceeds the limit, entries are evicted according
+	// to EvictionPolicy, and DeleteCallback is called for evicted values.
+	// Unlike ExpireInterval which purges unused entries periodically,
+	// it bounds the cache size all the time.
+	MaxEntries int
+
+	// MaxCost optionally limits the total cost of entries in the cache,
+	// the cost of an entry is calculated by CostFunc.
+	// Default is zero which means no limit.
+	MaxCost int64
+
+	// CostFunc optionally calculates the cost of a value, it is used
+	// with MaxCost. If it is nil, or an entry has no value (e.g. a cached
+	// error), the cost of the entry is 1.
+	CostFunc func(key string, value any) int64
+
+	// EvictionPolicy specifies which entries are evicted when the cache
+	// exceeds MaxEntries or MaxCost, default is EvictLRU.
+	EvictionPolicy EvictionPolicy
 }
 
 func (p *Options) validate() {
@@ -83,26 +137,43 @@ func (p *Options) validate() {
 	}
 }
 
+func (p *Options) toTypedOptions() TypedOptions[string, any] {
+	return TypedOptions[string, any]{
+		Fetcher:         p.Fetcher,
+		FetchTimeout:    p.FetchTimeout,
+		RefreshInterval: p.RefreshInterval,
+		ExpireInterval:  p.ExpireInterval,
+		MaxStaleness:    p.MaxStaleness,
+		BlockOnStale:    p.BlockOnStale,
+		ErrorTTL:        p.ErrorTTL,
+		NotFoundTTL:     p.NotFoundTTL,
+		ErrorCallback:   p.ErrorCallback,
+		ChangeCallback:  p.ChangeCallback,
+		DeleteCallback:  p.DeleteCallback,
+
+		Name:                p.Name,
+		StatsExporter:       p.StatsExporter,
+		StatsExportInterval: p.StatsExportInterval,
+
+		MaxEntries:     p.MaxEntries,
+		MaxCost:        p.MaxCost,
+		CostFunc:       p.CostFunc,
+		EvictionPolicy: p.EvictionPolicy,
+	}
+}
+
 // Cache is an asynchronous cache which prevents duplicate functions calls
 // that is massive or maybe expensive, or some data which rarely change,
 // and we want to get it quickly.
 //
+// Cache is a thin wrapper of TypedCache[string, any],
+// new code should consider using TypedCache instead.
+//
 // Zero value of Cache is not ready to use. Use the function NewCache to
 // make a new Cache instance. A Cache value shall not be copied after
 // initialized.
 type Cache struct {
-	opt     Options
-	sfGroup singleflight.Group
-	data    sync.Map
-
-	mu           sync.Mutex
-	refreshQueue *heapx.PriorityQueue[int64, string]
-
-	ticker       *functicker.Ticker
-	preExpireAt  atomic.Int64
-	doingExpire  int32
-	doingRefresh int32
-	closed       int32
+	*TypedCache[string, any]
 }
 
 // NewCache returns a new Cache instance using the given options.
@@ -113,29 +184,25 @@ func NewCache(opt Options) *Cache {
 
 func newCacheWithTickInterval(opt Options, tickInterval time.Duration) *Cache {
 	opt.validate()
-	c := &Cache{
-		opt:          opt,
-		refreshQueue: heapx.NewMinPriorityQueue[int64, string](),
+	return &Cache{
+		TypedCache: newTypedCacheWithTickInterval(opt.toTypedOptions(), tickInterval),
 	}
-	if opt.ExpireInterval > 0 || opt.RefreshInterval > 0 {
-		c.ticker = functicker.New(tickInterval, c.runBackgroundTasks)
-	}
-	return c
 }
 
-// Close closes the Cache.
-// It signals the background goroutines to shut down.
+// GetOrDefault tries to fetch a value corresponding to the given key from
+// the cache. If it's not cached, a calling to Options.Fetcher
+// will be fired and the result will be cached.
 //
-// It should be called when the Cache is no longer needed,
-// or may lead resource leaks.
-func (c *Cache) Close() {
-	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
-		return
-	}
-	if c.ticker != nil {
-		c.ticker.Stop()
-		c.ticker = nil
+// If error occurs during the first fetching, defaultVal will be set into
+// the cache and returned, the default value will also be used for
+// further calling of Get and GetOrDefault.
+// A nil defaultVal is not cached, the error is cached instead.
+func (c *Cache) GetOrDefault(key string, defaultVal any) any {
+	value, err := c.get(key, defaultVal, defaultVal != nil)
+	if err != nil {
+		value = defaultVal
 	}
+	return value
 }
 
 // SetDefault sets the default value of a given key if it is new to the cache.
@@ -148,15 +215,7 @@ func (c *Cache) SetDefault(key string, value any) (exists bool) {
 	if value == nil {
 		panic("acache: value must not be nil")
 	}
-	nowNano := time.Now().UnixNano()
-	ent := allocEntry(value, errDefaultVal, nowNano)
-	actual, loaded := c.data.LoadOrStore(key, ent)
-	if loaded {
-		actual.(*entry).MarkActive()
-	} else {
-		c.addToRefreshQueue(nowNano, key)
-	}
-	return loaded
+	return c.TypedCache.SetDefault(key, value)
 }
 
 // Update sets a value for key into the cache.
@@ -166,423 +225,9 @@ func (c *Cache) Update(key string, value any) {
 	if value == nil {
 		panic("acache: value must not be nil")
 	}
-	nowNano := time.Now().UnixNano()
-	val, ok := c.data.Load(key)
-	if ok {
-		ent := val.(*entry)
-		ent.Store(value, nil, nowNano)
-	} else {
-		ent := allocEntry(value, nil, nowNano)
-		c.data.Store(key, ent)
-	}
-	c.addToRefreshQueue(nowNano, key)
-}
-
-// Contains tells whether the cache contains the specified key.
-// It returns false if key is never accessed from the cache,
-// true means that a value or an error for key exists in the cache.
-func (c *Cache) Contains(key string) bool {
-	_, ok := c.data.Load(key)
-	return ok
-}
-
-// Get tries to fetch a value corresponding to the given key from the cache.
-// If it's not cached, a calling to Fetcher.Fetch will be fired
-// and the result will be cached.
-//
-// If error occurs during the first fetching, the error will be cached until
-// the subsequent fetching requests triggered by refreshing succeed.
-// The cached error will be returned, it does not trigger a calling to
-// Options.Fetcher again.
-//
-// If a default value is set by SetDefault, the default value will be used,
-// it does not trigger a calling to Options.Fetcher.
-func (c *Cache) Get(key string) (any, error) {
-	val, ok := c.data.Load(key)
-	if ok {
-		ent := val.(*entry)
-		ent.MarkActive()
-		value, err := ent.Load()
-		if err == errDefaultVal {
-			err = nil
-		}
-		return value, err
-	}
-
-	// Wait the fetch function to get result.
-	return c.doFetch(key, nil)
-}
-
-// GetOrDefault tries to fetch a value corresponding to the given key from
-// the cache. If it's not cached, a calling to Options.Fetcher
-// will be fired and the result will be cached.
-//
-// If error occurs during the first fetching, defaultVal will be set into
-// the cache and returned, the default value will also be used for
-// further calling of Get and GetOrDefault.
-func (c *Cache) GetOrDefault(key string, defaultVal any) any {
-	val, ok := c.data.Load(key)
-	if ok {
-		ent := val.(*entry)
-		ent.MarkActive()
-		value, err := ent.Load()
-		if err != nil {
-			value = defaultVal
-		}
-		return value
-	}
-
-	// Fetch result from upstream or use the default value
-	val, _ = c.doFetch(key, defaultVal)
-	return val
-}
-
-func (c *Cache) doFetch(key string, defaultVal any) (any, error) {
-	if c.opt.FetchTimeout == 0 {
-		return c.fetchNoTimeout(key, defaultVal)
-	}
-	return c.fetchWithTimeout(key, defaultVal)
-}
-
-func (c *Cache) fetchNoTimeout(key string, defaultVal any) (any, error) {
-	val, err, _ := c.sfGroup.Do(key, func() (any, error) {
-		val, err := c.opt.Fetcher.Fetch(key)
-		if err != nil && defaultVal != nil {
-			val, err = defaultVal, errDefaultVal
-		}
-		nowNano := time.Now().UnixNano()
-		ent := allocEntry(val, err, nowNano)
-		c.data.Store(key, ent)
-		c.addToRefreshQueue(nowNano, key)
-		return val, err
-	})
-	return val, err
-}
-
-func (c *Cache) fetchWithTimeout(key string, defaultVal any) (any, error) {
-	timeout := time.NewTimer(c.opt.FetchTimeout)
-	ch := c.sfGroup.DoChan(key, func() (any, error) {
-		val, err := c.opt.Fetcher.Fetch(key)
-		if err != nil && defaultVal != nil {
-			val, err = defaultVal, errDefaultVal
-		}
-		nowNano := time.Now().UnixNano()
-		ent := allocEntry(val, err, nowNano)
-		c.data.Store(key, ent)
-		c.addToRefreshQueue(nowNano, key)
-		return val, err
-	})
-	select {
-	case <-timeout.C:
-		return nil, ErrFetchTimeout
-	case result := <-ch:
-		timeout.Stop()
-		return result.Val, result.Err
-	}
-}
-
-// Delete deletes the entry of key from the cache if it exists.
-func (c *Cache) Delete(key string) {
-	val, ok := c.data.Load(key)
-	if ok {
-		ent := val.(*entry)
-		value, _ := ent.Load()
-		if value != nil && c.opt.DeleteCallback != nil {
-			c.opt.DeleteCallback(key, value)
-		}
-		c.data.Delete(key)
-	}
-}
-
-// DeleteFunc iterates the cache and deletes entries that the key matches
-// the given function.
-func (c *Cache) DeleteFunc(match func(key string) bool) {
-	hasDeleteCallback := c.opt.DeleteCallback != nil
-	c.data.Range(func(key, val any) bool {
-		keystr := key.(string)
-		if match(keystr) {
-			if hasDeleteCallback {
-				ent := val.(*entry)
-				value, _ := ent.Load()
-				if value != nil {
-					c.opt.DeleteCallback(keystr, value)
-				}
-			}
-			c.data.Delete(key)
-		}
-		return true
-	})
-}
-
-func (c *Cache) addToRefreshQueue(updateAtNano int64, key string) {
-	c.mu.Lock()
-	c.refreshQueue.Push(updateAtNano, key)
-	c.mu.Unlock()
-}
-
-func (c *Cache) runBackgroundTasks() {
-	if atomic.LoadInt32(&c.closed) > 0 {
-		return
-	}
-	if c.opt.ExpireInterval > 0 {
-		c.doExpire(false)
-	}
-	if c.opt.RefreshInterval > 0 {
-		if atomic.CompareAndSwapInt32(&c.doingRefresh, 0, 1) {
-			c.doRefresh()
-			atomic.StoreInt32(&c.doingRefresh, 0)
-		}
-	}
+	c.TypedCache.Update(key, value)
 }
 
-func (c *Cache) doExpire(force bool) {
-	nowUnix := time.Now().Unix()
-	preExpireAt := c.preExpireAt.Load()
-
-	// "force" helps to do unittest.
-	if !force {
-		if preExpireAt == 0 {
-			c.preExpireAt.Store(nowUnix)
-			return
-		}
-		if time.Duration(nowUnix-preExpireAt)*time.Second < c.opt.ExpireInterval {
-			return
-		}
-	}
-
-	hasDeleteCallback := c.opt.DeleteCallback != nil
-	if atomic.CompareAndSwapInt32(&c.doingExpire, 0, 1) {
-		defer atomic.StoreInt32(&c.doingExpire, 0)
-		c.preExpireAt.Store(nowUnix)
-		c.data.Range(func(key, val any) bool {
-			keystr := key.(string)
-			ent := val.(*entry)
-
-			// If entry.expire is "active", we mark it as "inactive" here.
-			// Then during the next execution, "inactive" entries will be deleted.
-			isActive := atomic.CompareAndSwapInt32(&ent.expire, active, inactive)
-			if !isActive {
-				if hasDeleteCallback {
-					value, _ := ent.Load()
-					if value != nil {
-						c.opt.DeleteCallback(keystr, val)
-					}
-				}
-				c.data.Delete(key)
-			}
-			return true
-		})
-	}
-}
-
-func (c *Cache) needRefresh(nowNano, updateAtNano int64) bool {
-	return time.Duration(nowNano-updateAtNano) >= c.opt.RefreshInterval
-}
-
-func (c *Cache) checkEntryNeedRefresh(nowNano, updateAtNano int64, key string) (ent *entry, refresh bool) {
-	val, _ := c.data.Load(key)
-	if val == nil {
-		// The data has already been deleted.
-		return nil, false
-	}
-	ent = val.(*entry)
-	if ent.GetUpdateAt() != updateAtNano {
-		// The data has already been changed.
-		return ent, false
-	}
-	if !c.needRefresh(nowNano, updateAtNano) {
-		return ent, false
-	}
-	return ent, true
-}
-
-func (c *Cache) doRefresh() {
-	if _, ok := c.opt.Fetcher.(BatchFetcher); ok {
-		c.doBatchRefresh()
-		return
-	}
-
-	hasErrorCallback := c.opt.ErrorCallback != nil
-	hasChangeCallback := c.opt.ChangeCallback != nil
-	for {
-		nowNano := time.Now().UnixNano()
-		needRefresh := false
-
-		c.mu.Lock()
-		updateAt, key, ok := c.refreshQueue.Peek()
-		if ok && c.needRefresh(nowNano, updateAt) {
-			c.refreshQueue.Pop()
-			needRefresh = true
-		}
-		c.mu.Unlock()
-		if !needRefresh {
-			break
-		}
-
-		var ent *entry
-		ent, needRefresh = c.checkEntryNeedRefresh(nowNano, updateAt, key)
-		if !needRefresh {
-			continue
-		}
-		newVal, err := c.opt.Fetcher.Fetch(key)
-		if err != nil {
-			if hasErrorCallback {
-				c.opt.ErrorCallback(err, []string{key})
-			}
-			_, oldErr := ent.Load()
-			if oldErr != nil {
-				ent.SetError(err)
-			}
-		} else {
-			// Save the new value from upstream.
-			if hasChangeCallback {
-				oldVal, _ := ent.Load()
-				c.opt.ChangeCallback(key, oldVal, newVal)
-			}
-			ent.Store(newVal, nil, nowNano)
-		}
-		c.addToRefreshQueue(nowNano, key)
-	}
-}
-
-type refreshQueueItem struct {
-	key   string
-	tNano int64
-}
-
-func (c *Cache) doBatchRefresh() {
-	fetcher := c.opt.Fetcher.(BatchFetcher)
-	batchSize := fetcher.BatchSize()
-	expiredItems := make([]refreshQueueItem, 0, batchSize)
-	keys := make([]string, 0, batchSize)
-	for {
-		nowNano := time.Now().UnixNano()
-		expiredItems = expiredItems[:0]
-		keys = keys[:0]
-
-		c.mu.Lock()
-		for len(expiredItems) < batchSize {
-			updateAt, key, ok := c.refreshQueue.Peek()
-			if !ok || !c.needRefresh(nowNano, updateAt) {
-				break
-			}
-			c.refreshQueue.Pop()
-			expiredItems = append(expiredItems, refreshQueueItem{key, updateAt})
-		}
-		c.mu.Unlock()
-
-		for _, item := range expiredItems {
-			_, needRefresh := c.checkEntryNeedRefresh(nowNano, item.tNano, item.key)
-			if !needRefresh {
-				continue
-			}
-			keys = append(keys, item.key)
-		}
-		if len(keys) > 0 {
-			c.batchRefreshKeys(keys)
-		}
-
-		// No more expired data to refresh.
-		if len(expiredItems) < batchSize {
-			break
-		}
-	}
-}
-
-func (c *Cache) batchRefreshKeys(keys []string) {
-	nowNano := time.Now().UnixNano()
-	fetcher := c.opt.Fetcher.(BatchFetcher)
-	newValMap, err := fetcher.BatchFetch(keys)
-	if err != nil {
-		hasErrorCallback := c.opt.ErrorCallback != nil
-		if hasErrorCallback {
-			c.opt.ErrorCallback(err, keys)
-		}
-		for _, key := range keys {
-			val, _ := c.data.Load(key)
-			if val == nil {
-				continue
-			}
-			ent := val.(*entry)
-			_, oldErr := ent.Load()
-			if oldErr != nil {
-				ent.SetError(err)
-			}
-		}
-		return
-	}
-	hasChangeCallback := c.opt.ChangeCallback != nil
-	for key, newVal := range newValMap {
-		entVal, _ := c.data.Load(key)
-		if entVal == nil {
-			continue
-		}
-		ent := entVal.(*entry)
-		if hasChangeCallback {
-			oldVal, _ := ent.Load()
-			c.opt.ChangeCallback(key, oldVal, newVal)
-		}
-		ent.Store(newVal, nil, nowNano)
-		c.addToRefreshQueue(nowNano, key)
-	}
-}
-
-func allocEntry(val any, err error, updateAtNano int64) *entry {
-	ent := &entry{}
-	ent.Store(val, err, updateAtNano)
-	return ent
-}
-
-type entry struct {
-	val      atomic.Value
-	errp     unsafe.Pointer // *error
-	updateAt int64
-	expire   int32
-}
-
-func (e *entry) Load() (any, error) {
-	val := e.val.Load()
-	errp := atomic.LoadPointer(&e.errp)
-	if errp == nil {
-		return val, nil
-	}
-	return val, *(*error)(errp)
-}
-
-func (e *entry) Store(val any, err error, updateAtNano int64) {
-	if err != nil {
-		atomic.StorePointer(&e.errp, unsafe.Pointer(&err))
-		if val != nil {
-			e.val.Store(val)
-		}
-	} else if val != nil {
-		e.val.Store(val)
-		atomic.StorePointer(&e.errp, nil)
-	}
-	e.SetUpdateAt(updateAtNano)
-}
-
-func (e *entry) SetError(err error) {
-	atomic.StorePointer(&e.errp, unsafe.Pointer(&err))
-}
-
-func (e *entry) GetUpdateAt() int64 {
-	return atomic.LoadInt64(&e.updateAt)
-}
-
-func (e *entry) SetUpdateAt(updateAtNano int64) {
-	atomic.StoreInt64(&e.updateAt, updateAtNano)
-}
-
-func (e *entry) MarkActive() {
-	atomic.StoreInt32(&e.expire, active)
-}
-
-const (
-	active   = 0
-	inactive = 1
-)
-
 var errDefaultVal = tombError(1)
 
 type tombError int
@@ -145,6 +145,24 @@ func TestGetOrDefaultError(t *testing.T) {
 	assert.Equal(t, val, got)
 }
 
+func TestGetOrDefaultNil(t *testing.T) {
+	opt := Options{
+		Fetcher: FuncFetcher(func(key string) (any, error) {
+			return nil, errors.New("error")
+		}),
+	}
+	c := newCacheWithTickInterval(opt, 10*time.Millisecond)
+	defer c.Close()
+
+	// A nil default value is not cached, the error is cached instead.
+	got := c.GetOrDefault("key", nil)
+	assert.Nil(t, got)
+	_, err := c.Get("key")
+	assert.NotNil(t, err)
+	got = c.GetOrDefault("key", "default")
+	assert.Equal(t, "default", got)
+}
+
 func TestSetDefault(t *testing.T) {
 	opt := Options{
 		RefreshInterval: 50 * time.Millisecond,
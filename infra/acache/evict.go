@@ -0,0 +1,485 @@
+package acache
+
+import (
+	"cmp"
+	"container/heap"
+	"container/list"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+// EvictionPolicy specifies which entries are evicted when the cache
+// exceeds TypedOptions.MaxEntries or TypedOptions.MaxCost.
+type EvictionPolicy int
+
+const (
+	// EvictLRU evicts the least recently used entries.
+	EvictLRU EvictionPolicy = iota
+
+	// EvictLFU evicts the least frequently used entries,
+	// entries with same frequency are evicted in LRU order.
+	// Frequencies are halved periodically, thus keys which were hot
+	// long ago can be evicted.
+	EvictLFU
+)
+
+// evictor tracks keys in the cache to choose victims for eviction.
+// It is not safe for concurrent use, the caller must hold a lock.
+type evictor[K comparable] interface {
+	// add adds a new key or updates an existing key, it marks the key
+	// as accessed.
+	add(key K, cost int64)
+
+	// access marks the key as accessed.
+	access(key K)
+
+	// setCost updates cost of an existing key.
+	setCost(key K, cost int64)
+
+	// remove removes key from the evictor.
+	remove(key K)
+
+	// victim returns the key which should be evicted first,
+	// excluding the given key.
+	victim(exclude K) (K, bool)
+
+	len() int
+	totalCost() int64
+}
+
+func newEvictor[K comparable](policy EvictionPolicy) evictor[K] {
+	if policy == EvictLFU {
+		return &lfuEvictor[K]{items: make(map[K]*lfuItem[K])}
+	}
+	return &lruEvictor[K]{
+		list:  list.New(),
+		items: make(map[K]*list.Element),
+	}
+}
+
+type lruNode[K comparable] struct {
+	key  K
+	cost int64
+}
+
+type lruEvictor[K comparable] struct {
+	list  *list.List // front is the most recently used
+	items map[K]*list.Element
+	cost  int64
+}
+
+func (e *lruEvictor[K]) add(key K, cost int64) {
+	if elem, ok := e.items[key]; ok {
+		node := elem.Value.(*lruNode[K])
+		e.cost += cost - node.cost
+		node.cost = cost
+		e.list.MoveToFront(elem)
+		return
+	}
+	e.items[key] = e.list.PushFront(&lruNode[K]{key: key, cost: cost})
+	e.cost += cost
+}
+
+func (e *lruEvictor[K]) access(key K) {
+	if elem, ok := e.items[key]; ok {
+		e.list.MoveToFront(elem)
+	}
+}
+
+func (e *lruEvictor[K]) setCost(key K, cost int64) {
+	if elem, ok := e.items[key]; ok {
+		node := elem.Value.(*lruNode[K])
+		e.cost += cost - node.cost
+		node.cost = cost
+	}
+}
+
+func (e *lruEvictor[K]) remove(key K) {
+	if elem, ok := e.items[key]; ok {
+		e.cost -= elem.Value.(*lruNode[K]).cost
+		e.list.Remove(elem)
+		delete(e.items, key)
+	}
+}
+
+func (e *lruEvictor[K]) victim(exclude K) (key K, ok bool) {
+	for elem := e.list.Back(); elem != nil; elem = elem.Prev() {
+		if key = elem.Value.(*lruNode[K]).key; key != exclude {
+			return key, true
+		}
+	}
+	return key, false
+}
+
+func (e *lruEvictor[K]) len() int         { return len(e.items) }
+func (e *lruEvictor[K]) totalCost() int64 { return e.cost }
+
+type lfuItem[K comparable] struct {
+	key   K
+	cost  int64
+	freq  int64
+	seq   int64 // sequence number of the last access
+	index int
+}
+
+// lfuAgingFactor and lfuMinAgingOps control how often lfuEvictor ages
+// the frequencies, frequencies are halved after
+// max(lfuAgingFactor*len, lfuMinAgingOps) accesses.
+const (
+	lfuAgingFactor = 10
+	lfuMinAgingOps = 1024
+)
+
+type lfuEvictor[K comparable] struct {
+	heap  lfuHeap[K]
+	items map[K]*lfuItem[K]
+	seq   int64
+	cost  int64
+	ops   int64 // accesses since last aging
+}
+
+func (e *lfuEvictor[K]) add(key K, cost int64) {
+	e.seq++
+	if item, ok := e.items[key]; ok {
+		e.cost += cost - item.cost
+		item.cost = cost
+		item.freq++
+		item.seq = e.seq
+		heap.Fix(&e.heap, item.index)
+		e.incrOps()
+		return
+	}
+	item := &lfuItem[K]{key: key, cost: cost, freq: 1, seq: e.seq}
+	e.items[key] = item
+	heap.Push(&e.heap, item)
+	e.cost += cost
+}
+
+func (e *lfuEvictor[K]) access(key K) {
+	if item, ok := e.items[key]; ok {
+		e.seq++
+		item.freq++
+		item.seq = e.seq
+		heap.Fix(&e.heap, item.index)
+		e.incrOps()
+	}
+}
+
+func (e *lfuEvictor[K]) incrOps() {
+	e.ops++
+	if e.ops >= max(lfuAgingFactor*int64(len(e.items)), lfuMinAgingOps) {
+		e.age()
+	}
+}
+
+// age halves the frequencies, so that keys which were hot long ago
+// do not stay in the cache forever.
+func (e *lfuEvictor[K]) age() {
+	e.ops = 0
+	for _, item := range e.heap {
+		item.freq = max(item.freq/2, 1)
+	}
+	heap.Init(&e.heap)
+}
+
+func (e *lfuEvictor[K]) setCost(key K, cost int64) {
+	if item, ok := e.items[key]; ok {
+		e.cost += cost - item.cost
+		item.cost = cost
+	}
+}
+
+func (e *lfuEvictor[K]) remove(key K) {
+	if item, ok := e.items[key]; ok {
+		e.cost -= item.cost
+		heap.Remove(&e.heap, item.index)
+		delete(e.items, key)
+	}
+}
+
+func (e *lfuEvictor[K]) victim(exclude K) (key K, ok bool) {
+	h := e.heap
+	if len(h) == 0 {
+		return key, false
+	}
+	if h[0].key != exclude {
+		return h[0].key, true
+	}
+	// The second least item must be one of the children of the root.
+	switch {
+	case len(h) == 1:
+		return key, false
+	case len(h) == 2 || h.Less(1, 2):
+		return h[1].key, true
+	default:
+		return h[2].key, true
+	}
+}
+
+func (e *lfuEvictor[K]) len() int         { return len(e.items) }
+func (e *lfuEvictor[K]) totalCost() int64 { return e.cost }
+
+type lfuHeap[K comparable] []*lfuItem[K]
+
+func (h lfuHeap[K]) Len() int { return len(h) }
+
+func (h lfuHeap[K]) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h lfuHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap[K]) Push(x any) {
+	item := x.(*lfuItem[K])
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *lfuHeap[K]) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+const (
+	accessStripes   = 16
+	accessBatchSize = 64
+)
+
+// clockBase is used to get monotonic timestamps of accesses.
+var clockBase = time.Now()
+
+type accessRecord[K comparable] struct {
+	key K
+	at  time.Duration
+}
+
+type accessStripe[K comparable] struct {
+	mu      sync.Mutex
+	records []accessRecord[K]
+	_       [64]byte // avoid false sharing
+}
+
+// accessBuffer buffers accesses of keys, so that a cache hit does not
+// take the evictor lock. Accesses are recorded into randomly chosen
+// stripes, and applied to the evictor in batches in time order.
+type accessBuffer[K comparable] struct {
+	stripes [accessStripes]accessStripe[K]
+	merged  []accessRecord[K] // protected by evictMu
+}
+
+// add records an access of key, it returns true if a stripe is full,
+// then the caller should apply the buffered accesses.
+func (b *accessBuffer[K]) add(key K) (full bool) {
+	s := &b.stripes[rand.Uint32()%accessStripes]
+	rec := accessRecord[K]{key: key, at: time.Since(clockBase)}
+	s.mu.Lock()
+	s.records = append(s.records, rec)
+	full = len(s.records) >= accessBatchSize
+	s.mu.Unlock()
+	return full
+}
+
+// drain removes the buffered accesses and returns them in time order.
+// The caller must hold evictMu and must not modify the result.
+func (b *accessBuffer[K]) drain() []accessRecord[K] {
+	merged := b.merged[:0]
+	for i := range b.stripes {
+		s := &b.stripes[i]
+		s.mu.Lock()
+		merged = append(merged, s.records...)
+		clear(s.records)
+		s.records = s.records[:0]
+		s.mu.Unlock()
+	}
+	slices.SortFunc(merged, func(a, b accessRecord[K]) int {
+		return cmp.Compare(a.at, b.at)
+	})
+	b.merged = merged
+	return merged
+}
+
+// lockEvictor acquires c.evictMu and applies the buffered accesses,
+// thus the evictor sees the accesses before it is changed.
+func (c *TypedCache[K, V]) lockEvictor() {
+	c.evictMu.Lock()
+	c.applyAccessesLocked()
+}
+
+func (c *TypedCache[K, V]) applyAccessesLocked() {
+	records := c.accesses.drain()
+	for i := range records {
+		c.evictor.access(records[i].key)
+	}
+	clear(records)
+}
+
+// evictionEnabled tells whether the cache limits its size.
+func (c *TypedCache[K, V]) evictionEnabled() bool {
+	return c.evictor != nil
+}
+
+func (c *TypedCache[K, V]) entryCost(key K, data *entryData[V]) int64 {
+	if c.opt.CostFunc == nil || !data.hasVal {
+		return 1
+	}
+	return c.opt.CostFunc(key, data.val)
+}
+
+// storeEntry stores a new entry into the cache, replacing the old one
+// if it exists, it evicts entries if the cache exceeds the limits.
+func (c *TypedCache[K, V]) storeEntry(key K, ent *entry[V]) {
+	if !c.evictionEnabled() {
+		c.data.Store(key, ent)
+		return
+	}
+	c.lockEvictor()
+	c.data.Store(key, ent)
+	c.evictor.add(key, c.entryCost(key, ent.Load()))
+	victims := c.evictLocked(key)
+	c.evictMu.Unlock()
+	c.onEvict(victims)
+}
+
+// loadOrStoreEntry is like storeEntry, but it does not replace an
+// existing entry.
+func (c *TypedCache[K, V]) loadOrStoreEntry(key K, ent *entry[V]) (actual *entry[V], loaded bool) {
+	if !c.evictionEnabled() {
+		val, loaded := c.data.LoadOrStore(key, ent)
+		return val.(*entry[V]), loaded
+	}
+	c.lockEvictor()
+	val, loaded := c.data.LoadOrStore(key, ent)
+	if loaded {
+		c.evictor.access(key)
+		c.evictMu.Unlock()
+		return val.(*entry[V]), true
+	}
+	c.evictor.add(key, c.entryCost(key, ent.Load()))
+	victims := c.evictLocked(key)
+	c.evictMu.Unlock()
+	c.onEvict(victims)
+	return ent, false
+}
+
+// updateEntry sets the value of key, it stores a new entry if key does
+// not exist. When eviction is enabled, the entry is loaded and changed
+// under c.evictMu, thus the value never lands on an evicted entry.
+func (c *TypedCache[K, V]) updateEntry(key K, value V, nowNano int64) {
+	if !c.evictionEnabled() {
+		if val, ok := c.data.Load(key); ok {
+			val.(*entry[V]).SetValue(value, nowNano)
+		} else {
+			c.data.Store(key, newEntry(&entryData[V]{val: value, hasVal: true, valAt: nowNano}, nowNano))
+		}
+		return
+	}
+	c.lockEvictor()
+	if val, ok := c.data.Load(key); ok {
+		ent := val.(*entry[V])
+		ent.SetValue(value, nowNano)
+		c.evictor.access(key)
+		if c.opt.CostFunc != nil {
+			c.evictor.setCost(key, c.entryCost(key, ent.Load()))
+		}
+	} else {
+		ent := newEntry(&entryData[V]{val: value, hasVal: true, valAt: nowNano}, nowNano)
+		c.data.Store(key, ent)
+		c.evictor.add(key, c.entryCost(key, ent.Load()))
+	}
+	victims := c.evictLocked(key)
+	c.evictMu.Unlock()
+	c.onEvict(victims)
+}
+
+// deleteEntry deletes the entry of key from the cache.
+// If ent is not nil, it deletes the entry only if it is the stored one.
+func (c *TypedCache[K, V]) deleteEntry(key K, ent *entry[V]) (*entry[V], bool) {
+	if c.evictionEnabled() {
+		c.lockEvictor()
+		defer c.evictMu.Unlock()
+	}
+	var deleted bool
+	if ent != nil {
+		deleted = c.data.CompareAndDelete(key, ent)
+	} else {
+		var val any
+		val, deleted = c.data.LoadAndDelete(key)
+		if deleted {
+			ent = val.(*entry[V])
+		}
+	}
+	if deleted && c.evictionEnabled() {
+		c.evictor.remove(key)
+	}
+	return ent, deleted
+}
+
+// touchEntry marks key as accessed for the eviction policy.
+// The access is buffered, it is applied to the evictor when the
+// buffer is full or before the evictor is changed.
+func (c *TypedCache[K, V]) touchEntry(key K) {
+	if c.evictionEnabled() && c.accesses.add(key) {
+		c.lockEvictor()
+		c.evictMu.Unlock()
+	}
+}
+
+// updateEntryCost updates cost of key after its value changed,
+// it evicts entries if the cache exceeds the limits.
+func (c *TypedCache[K, V]) updateEntryCost(key K, ent *entry[V]) {
+	if !c.evictionEnabled() || c.opt.CostFunc == nil {
+		return
+	}
+	c.lockEvictor()
+	c.evictor.setCost(key, c.entryCost(key, ent.Load()))
+	victims := c.evictLocked(key)
+	c.evictMu.Unlock()
+	c.onEvict(victims)
+}
+
+type evictedEntry[K comparable, V any] struct {
+	key K
+	ent *entry[V]
+}
+
+// evictLocked evicts entries until the cache satisfies the limits,
+// the key which is just added or updated is never evicted.
+// The caller must hold c.evictMu.
+func (c *TypedCache[K, V]) evictLocked(current K) (victims []evictedEntry[K, V]) {
+	for c.overLimit() {
+		key, ok := c.evictor.victim(current)
+		if !ok {
+			break
+		}
+		c.evictor.remove(key)
+		if val, deleted := c.data.LoadAndDelete(key); deleted {
+			victims = append(victims, evictedEntry[K, V]{key, val.(*entry[V])})
+		}
+	}
+	return victims
+}
+
+func (c *TypedCache[K, V]) overLimit() bool {
+	return (c.opt.MaxEntries > 0 && c.evictor.len() > c.opt.MaxEntries) ||
+		(c.opt.MaxCost > 0 && c.evictor.totalCost() > c.opt.MaxCost)
+}
+
+func (c *TypedCache[K, V]) onEvict(victims []evictedEntry[K, V]) {
+	for _, v := range victims {
+		c.stats.evictions.Add(1)
+		c.onDelete(v.key, v.ent)
+	}
+}
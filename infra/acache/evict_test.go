@@ -0,0 +1,181 @@
+package acache
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedCache_MaxEntriesLRU(t *testing.T) {
+	var mu sync.Mutex
+	var deleted []int
+	opt := TypedOptions[int, int]{
+		Fetcher: TypedFuncFetcher[int, int](func(key int) (int, error) {
+			return key * 10, nil
+		}),
+		MaxEntries: 3,
+		DeleteCallback: func(key int, data int) {
+			mu.Lock()
+			deleted = append(deleted, key)
+			mu.Unlock()
+		},
+	}
+	c := NewTypedCache(opt)
+	defer c.Close()
+
+	for i := 1; i <= 3; i++ {
+		_, _ = c.Get(i)
+	}
+	_, _ = c.Get(1) // 2 becomes the least recently used
+	_, _ = c.Get(4)
+	assert.Equal(t, []int{2}, deleted)
+	assert.False(t, c.Contains(2))
+	assert.True(t, c.Contains(1))
+
+	c.Update(5, 50)
+	c.SetDefault(6, 60)
+	assert.Equal(t, []int{2, 3, 1}, deleted)
+	assert.Equal(t, 3, c.Stats().Entries)
+	assert.Equal(t, int64(3), c.Stats().Evictions)
+
+	c.Delete(4)
+	_, _ = c.Get(7)
+	assert.Equal(t, []int{2, 3, 1, 4}, deleted)
+	assert.Equal(t, 3, c.evictor.len())
+}
+
+func TestTypedCache_MaxEntriesLFU(t *testing.T) {
+	var deleted []int
+	opt := TypedOptions[int, int]{
+		Fetcher: TypedFuncFetcher[int, int](func(key int) (int, error) {
+			return key, nil
+		}),
+		MaxEntries:     2,
+		EvictionPolicy: EvictLFU,
+		DeleteCallback: func(key int, data int) {
+			deleted = append(deleted, key)
+		},
+	}
+	c := NewTypedCache(opt)
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		_, _ = c.Get(1)
+	}
+	_, _ = c.Get(2)
+	_, _ = c.Get(3)
+	assert.Equal(t, []int{2}, deleted)
+
+	// A new key is never evicted immediately, though it has the
+	// lowest frequency.
+	_, _ = c.Get(3)
+	_, _ = c.Get(4)
+	assert.Equal(t, []int{2, 3}, deleted)
+	assert.True(t, c.Contains(1))
+	assert.True(t, c.Contains(4))
+}
+
+func TestTypedCache_MaxCost(t *testing.T) {
+	var deleted []string
+	opt := TypedOptions[string, string]{
+		Fetcher: TypedFuncFetcher[string, string](func(key string) (string, error) {
+			return key, nil
+		}),
+		MaxCost: 10,
+		CostFunc: func(key string, value string) int64 {
+			return int64(len(value))
+		},
+		DeleteCallback: func(key string, data string) {
+			deleted = append(deleted, key)
+		},
+	}
+	c := NewTypedCache(opt)
+	defer c.Close()
+
+	_, _ = c.Get("aaaa")
+	_, _ = c.Get("bbbb")
+	assert.Nil(t, deleted)
+	_, _ = c.Get("cccc")
+	assert.Equal(t, []string{"aaaa"}, deleted)
+	assert.Equal(t, int64(8), c.evictor.totalCost())
+
+	c.Update("cccc", "cccccccc")
+	assert.Equal(t, []string{"aaaa", "bbbb"}, deleted)
+	assert.Equal(t, int64(8), c.evictor.totalCost())
+}
+
+func TestTypedCache_EvictionRefresh(t *testing.T) {
+	var version atomic.Int64
+	opt := TypedOptions[int, int64]{
+		Fetcher: TypedFuncFetcher[int, int64](func(key int) (int64, error) {
+			return version.Load(), nil
+		}),
+		RefreshInterval: 30 * time.Millisecond,
+		MaxEntries:      2,
+	}
+	c := newTypedCacheWithTickInterval(opt, 10*time.Millisecond)
+	defer c.Close()
+
+	for i := 0; i < 5; i++ {
+		_, _ = c.Get(i)
+	}
+	version.Store(1)
+	time.Sleep(opt.RefreshInterval + 50*time.Millisecond)
+	assert.Equal(t, 2, c.Stats().Entries)
+	for _, key := range []int{3, 4} {
+		got, err := c.Get(key)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), got)
+	}
+}
+
+func TestLFUEvictorAging(t *testing.T) {
+	e := newEvictor[int](EvictLFU).(*lfuEvictor[int])
+	e.add(1, 1)
+	for i := 0; i < 2000; i++ {
+		e.access(1)
+	}
+	e.add(2, 1)
+	for i := 0; i < 1500; i++ {
+		e.access(2)
+	}
+
+	// Key 1 was hot long ago, it is evicted first after aging.
+	victim, ok := e.victim(0)
+	assert.True(t, ok)
+	assert.Equal(t, 1, victim)
+	assert.Less(t, e.items[1].freq, e.items[2].freq)
+}
+
+func TestTypedCache_ConcurrentGetWithEviction(t *testing.T) {
+	for _, policy := range []EvictionPolicy{EvictLRU, EvictLFU} {
+		opt := TypedOptions[int, int]{
+			Fetcher: TypedFuncFetcher[int, int](func(key int) (int, error) {
+				return key, nil
+			}),
+			MaxEntries:     100,
+			EvictionPolicy: policy,
+		}
+		c := NewTypedCache(opt)
+
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 5000; i++ {
+					key := (i * (g + 1)) % 200
+					got, err := c.Get(key)
+					assert.Nil(t, err)
+					assert.Equal(t, key, got)
+				}
+			}(g)
+		}
+		wg.Wait()
+		assert.LessOrEqual(t, c.Stats().Entries, 100)
+		c.Close()
+	}
+}
@@ -16,3 +16,28 @@ type FuncFetcher func(key string) (any, error)
 func (f FuncFetcher) Fetch(key string) (any, error) {
 	return f(key)
 }
+
+// TypedFetcher is the generic version of Fetcher, which is used by TypedCache.
+type TypedFetcher[K comparable, V any] interface {
+	Fetch(key K) (V, error)
+}
+
+// TypedBatchFetcher is the generic version of BatchFetcher,
+// which is used by TypedCache.
+type TypedBatchFetcher[K comparable, V any] interface {
+	TypedFetcher[K, V]
+	BatchSize() int
+	BatchFetch(keys []K) (map[K]V, error)
+}
+
+// TypedFuncFetcher is a function that implements the interface TypedFetcher.
+type TypedFuncFetcher[K comparable, V any] func(key K) (V, error)
+
+func (f TypedFuncFetcher[K, V]) Fetch(key K) (V, error) {
+	return f(key)
+}
+
+var (
+	_ TypedFetcher[string, any]      = Fetcher(nil)
+	_ TypedBatchFetcher[string, any] = BatchFetcher(nil)
+)
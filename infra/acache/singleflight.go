@@ -0,0 +1,106 @@
+package acache
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// sfPanicError is the error given to waiters of a call whose function
+// panicked.
+type sfPanicError struct {
+	value any
+	stack []byte
+}
+
+func (p *sfPanicError) Error() string {
+	return fmt.Sprintf("acache: fetch panicked: %v\n\n%s", p.value, p.stack)
+}
+
+// sfGroup is a generic version of golang.org/x/sync/singleflight.Group,
+// which supports keys of any comparable type.
+type sfGroup[K comparable, V any] struct {
+	mu sync.Mutex
+	m  map[K]*sfCall[V]
+}
+
+type sfCall[V any] struct {
+	wg    sync.WaitGroup
+	val   V
+	err   error
+	chans []chan<- sfResult[V]
+}
+
+type sfResult[V any] struct {
+	Val V
+	Err error
+}
+
+// Do executes and returns the results of the given function, making
+// sure that only one execution is in-flight for a given key at a time.
+func (g *sfGroup[K, V]) Do(key K, fn func() (V, error)) (V, error) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[K]*sfCall[V])
+	}
+	if c, ok := g.m[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err
+	}
+	c := new(sfCall[V])
+	c.wg.Add(1)
+	g.m[key] = c
+	g.mu.Unlock()
+
+	g.doCall(c, key, fn)
+	if p, ok := c.err.(*sfPanicError); ok {
+		panic(p.value)
+	}
+	return c.val, c.err
+}
+
+// DoChan is like Do but returns a channel that will receive the
+// results when they are ready.
+func (g *sfGroup[K, V]) DoChan(key K, fn func() (V, error)) <-chan sfResult[V] {
+	ch := make(chan sfResult[V], 1)
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[K]*sfCall[V])
+	}
+	if c, ok := g.m[key]; ok {
+		c.chans = append(c.chans, ch)
+		g.mu.Unlock()
+		return ch
+	}
+	c := &sfCall[V]{chans: []chan<- sfResult[V]{ch}}
+	c.wg.Add(1)
+	g.m[key] = c
+	g.mu.Unlock()
+
+	go g.doCall(c, key, fn)
+	return ch
+}
+
+// doCall calls fn and notifies the waiters. If fn panics, the panic is
+// recovered and the waiters receive an *sfPanicError, the caller of Do
+// which executes fn re-panics with the original value.
+func (g *sfGroup[K, V]) doCall(c *sfCall[V], key K, fn func() (V, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			var zero V
+			c.val = zero
+			c.err = &sfPanicError{value: r, stack: debug.Stack()}
+		}
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		c.wg.Done()
+		if g.m[key] == c {
+			delete(g.m, key)
+		}
+		for _, ch := range c.chans {
+			ch <- sfResult[V]{c.val, c.err}
+		}
+	}()
+	c.val, c.err = fn()
+}
@@ -0,0 +1,224 @@
+package acache
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshAfterFailure(t *testing.T) {
+	var fail atomic.Bool
+	var version atomic.Int64
+	opt := TypedOptions[string, int64]{
+		RefreshInterval: 30 * time.Millisecond,
+		Fetcher: TypedFuncFetcher[string, int64](func(key string) (int64, error) {
+			if fail.Load() {
+				return 0, errors.New("upstream error")
+			}
+			return version.Load(), nil
+		}),
+	}
+	c := newTypedCacheWithTickInterval(opt, 5*time.Millisecond)
+	defer c.Close()
+
+	got, err := c.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), got)
+
+	// Refresh fails, the old value is kept.
+	fail.Store(true)
+	version.Store(1)
+	time.Sleep(100 * time.Millisecond)
+	got, err = c.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), got)
+
+	// The key keeps being refreshed after failures.
+	fail.Store(false)
+	time.Sleep(100 * time.Millisecond)
+	got, err = c.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), got)
+}
+
+func TestMaxStaleness(t *testing.T) {
+	var fail atomic.Bool
+	var version atomic.Int64
+	newOpt := func(block bool) TypedOptions[string, int64] {
+		return TypedOptions[string, int64]{
+			RefreshInterval: 20 * time.Millisecond,
+			MaxStaleness:    80 * time.Millisecond,
+			BlockOnStale:    block,
+			Fetcher: TypedFuncFetcher[string, int64](func(key string) (int64, error) {
+				if fail.Load() {
+					return 0, errors.New("upstream error")
+				}
+				return version.Load(), nil
+			}),
+		}
+	}
+
+	t.Run("error", func(t *testing.T) {
+		fail.Store(false)
+		c := newTypedCacheWithTickInterval(newOpt(false), 5*time.Millisecond)
+		defer c.Close()
+
+		got, err := c.Get("key")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), got)
+
+		fail.Store(true)
+		time.Sleep(50 * time.Millisecond)
+		got, err = c.Get("key")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), got)
+
+		time.Sleep(50 * time.Millisecond)
+		got, err = c.Get("key")
+		assert.True(t, errors.Is(err, ErrStale))
+		assert.Contains(t, err.Error(), "upstream error")
+		assert.Equal(t, int64(0), got)
+
+		// Default values never become stale.
+		c.SetDefault("default", 100)
+		time.Sleep(100 * time.Millisecond)
+		got, err = c.Get("default")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(100), got)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		fail.Store(false)
+		version.Store(0)
+		opt := newOpt(true)
+		opt.RefreshInterval = 0
+		c := newTypedCacheWithTickInterval(opt, 5*time.Millisecond)
+		defer c.Close()
+
+		got, err := c.Get("key")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), got)
+
+		version.Store(1)
+		time.Sleep(100 * time.Millisecond)
+		got, err = c.Get("key")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), got)
+
+		fail.Store(true)
+		time.Sleep(100 * time.Millisecond)
+		got, err = c.Get("key")
+		assert.True(t, errors.Is(err, ErrStale))
+		assert.Equal(t, int64(1), got)
+	})
+
+	t.Run("block timeout", func(t *testing.T) {
+		var slow atomic.Bool
+		opt := TypedOptions[string, int64]{
+			FetchTimeout: 50 * time.Millisecond,
+			MaxStaleness: 50 * time.Millisecond,
+			BlockOnStale: true,
+			Fetcher: TypedFuncFetcher[string, int64](func(key string) (int64, error) {
+				if slow.Load() {
+					time.Sleep(time.Second)
+				}
+				return 1, nil
+			}),
+		}
+		c := newTypedCacheWithTickInterval(opt, 5*time.Millisecond)
+		defer c.Close()
+
+		got, err := c.Get("key")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), got)
+
+		slow.Store(true)
+		time.Sleep(80 * time.Millisecond)
+		start := time.Now()
+		got, err = c.Get("key")
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.True(t, errors.Is(err, ErrStale))
+		assert.True(t, errors.Is(err, ErrFetchTimeout))
+		assert.Equal(t, int64(1), got)
+	})
+}
+
+func TestErrorTTL(t *testing.T) {
+	var count atomic.Int64
+	opt := Options{
+		ErrorTTL:    30 * time.Millisecond,
+		NotFoundTTL: 100 * time.Millisecond,
+		Fetcher: FuncFetcher(func(key string) (any, error) {
+			n := count.Add(1)
+			if key == "missing" {
+				return nil, fmt.Errorf("key %s: %w", key, ErrNotFound)
+			}
+			if n%2 == 1 {
+				return nil, errors.New("upstream error")
+			}
+			return "val", nil
+		}),
+	}
+	c := newCacheWithTickInterval(opt, 5*time.Millisecond)
+	defer c.Close()
+
+	_, err := c.Get("key")
+	assert.NotNil(t, err)
+	_, err = c.Get("key")
+	assert.NotNil(t, err)
+	assert.Equal(t, int64(1), count.Load())
+
+	time.Sleep(40 * time.Millisecond)
+	got, err := c.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, "val", got)
+
+	count.Store(0)
+	_, err = c.Get("missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	got = c.GetOrDefault("missing", "default")
+	assert.Equal(t, "default", got)
+	time.Sleep(40 * time.Millisecond)
+	_, err = c.Get("missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.Equal(t, int64(1), count.Load())
+
+	time.Sleep(70 * time.Millisecond)
+	_, err = c.Get("missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.Equal(t, int64(2), count.Load())
+}
+
+func TestNotFoundOnRefresh(t *testing.T) {
+	var deleted atomic.Bool
+	var errCallback atomic.Int64
+	opt := TypedOptions[string, string]{
+		RefreshInterval: 20 * time.Millisecond,
+		Fetcher: TypedFuncFetcher[string, string](func(key string) (string, error) {
+			if deleted.Load() {
+				return "", ErrNotFound
+			}
+			return "val", nil
+		}),
+		ErrorCallback: func(err error, keys []string) {
+			errCallback.Add(1)
+		},
+	}
+	c := newTypedCacheWithTickInterval(opt, 5*time.Millisecond)
+	defer c.Close()
+
+	got, err := c.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, "val", got)
+
+	deleted.Store(true)
+	time.Sleep(50 * time.Millisecond)
+	got, err = c.Get("key")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.Equal(t, "", got)
+	assert.Equal(t, int64(0), errCallback.Load())
+}
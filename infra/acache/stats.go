@@ -0,0 +1,240 @@
+package acache
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the statistics of a cache.
+type Stats struct {
+
+	// Name is the name of the cache, see TypedOptions.Name.
+	Name string
+
+	// Hits is the number of Get calls which are served by cached
+	// values or cached errors.
+	Hits int64
+
+	// Misses is the number of Get calls which trigger a fetch
+	// from upstream.
+	Misses int64
+
+	// Fetches is the number of calls to Fetcher, including fetches
+	// triggered by Get and refresh, a BatchFetch call counts as one.
+	Fetches int64
+
+	// FetchErrors is the number of fetches which return an error,
+	// not found results are not counted.
+	FetchErrors int64
+
+	// NotFounds is the number of fetches which return ErrNotFound.
+	NotFounds int64
+
+	// ExpiredDeletes is the number of entries deleted by the
+	// expiration of ExpireInterval.
+	ExpiredDeletes int64
+
+	// Evictions is the number of entries evicted due to MaxEntries
+	// or MaxCost.
+	Evictions int64
+
+	// Entries is the number of entries in the cache.
+	Entries int
+
+	// RefreshQueueLen is the length of the refresh queue.
+	RefreshQueueLen int
+
+	// FetchLatency is the latency histogram of fetches.
+	FetchLatency LatencyHistogram
+}
+
+// LatencyHistogram is a snapshot of a latency histogram.
+type LatencyHistogram struct {
+
+	// Buckets is the upper bounds of buckets, from 1ms to 10s.
+	Buckets []time.Duration
+
+	// Counts is the number of observations which fall into each bucket,
+	// it has one more element than Buckets, the last element counts
+	// observations which are larger than the last bucket upper bound.
+	// The counts are not cumulative.
+	Counts []int64
+
+	// Count is the total number of observations.
+	Count int64
+
+	// Sum is the sum of all observations.
+	Sum time.Duration
+}
+
+// StatsExporter receives statistics of a cache periodically,
+// it can be used to export cache metrics to a monitoring system.
+// See TypedOptions.StatsExporter.
+type StatsExporter interface {
+	ExportStats(stats Stats)
+}
+
+// StatsExporterFunc is a function that implements the interface StatsExporter.
+type StatsExporterFunc func(stats Stats)
+
+func (f StatsExporterFunc) ExportStats(stats Stats) {
+	f(stats)
+}
+
+// KeyInfo describes the state of a cached key,
+// it is mainly used to build debug pages.
+type KeyInfo[K comparable] struct {
+	Key K
+
+	// HasValue tells whether the key has a valid value in the cache.
+	HasValue bool
+
+	// IsDefault tells whether the value is a default value set by
+	// SetDefault or GetOrDefault.
+	IsDefault bool
+
+	// UpdatedAt is the time when the value is last fetched successfully,
+	// it is zero if HasValue is false.
+	UpdatedAt time.Time
+
+	// RefreshedAt is the time when the key is last fetched or refreshed,
+	// no matter whether the fetch succeeded or not.
+	RefreshedAt time.Time
+
+	// Err is the error of the last fetch, it is nil if the last fetch
+	// succeeded.
+	Err error
+
+	// ErrorAt is the time when Err occurred.
+	ErrorAt time.Time
+}
+
+type cacheStats struct {
+	hits           atomic.Int64
+	misses         atomic.Int64
+	fetches        atomic.Int64
+	fetchErrors    atomic.Int64
+	notFounds      atomic.Int64
+	expiredDeletes atomic.Int64
+	evictions      atomic.Int64
+
+	latencyCount  atomic.Int64
+	latencySum    atomic.Int64
+	latencyCounts [len(latencyBuckets) + 1]atomic.Int64
+
+	preExportAt atomic.Int64
+}
+
+var latencyBuckets = [...]time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+func (s *cacheStats) observeFetch(latency time.Duration, err error) {
+	s.fetches.Add(1)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			s.notFounds.Add(1)
+		} else {
+			s.fetchErrors.Add(1)
+		}
+	}
+	s.latencyCount.Add(1)
+	s.latencySum.Add(int64(latency))
+	idx := len(latencyBuckets)
+	for i, upper := range latencyBuckets {
+		if latency <= upper {
+			idx = i
+			break
+		}
+	}
+	s.latencyCounts[idx].Add(1)
+}
+
+func (s *cacheStats) snapshot(name string) Stats {
+	out := Stats{
+		Name:           name,
+		Hits:           s.hits.Load(),
+		Misses:         s.misses.Load(),
+		Fetches:        s.fetches.Load(),
+		FetchErrors:    s.fetchErrors.Load(),
+		NotFounds:      s.notFounds.Load(),
+		ExpiredDeletes: s.expiredDeletes.Load(),
+		Evictions:      s.evictions.Load(),
+		FetchLatency: LatencyHistogram{
+			Buckets: append([]time.Duration(nil), latencyBuckets[:]...),
+			Counts:  make([]int64, len(latencyBuckets)+1),
+			Count:   s.latencyCount.Load(),
+			Sum:     time.Duration(s.latencySum.Load()),
+		},
+	}
+	for i := range s.latencyCounts {
+		out.FetchLatency.Counts[i] = s.latencyCounts[i].Load()
+	}
+	return out
+}
+
+// Stats returns a snapshot of the statistics of the cache.
+func (c *TypedCache[K, V]) Stats() Stats {
+	out := c.stats.snapshot(c.opt.Name)
+	c.data.Range(func(_, _ any) bool {
+		out.Entries++
+		return true
+	})
+	c.mu.Lock()
+	out.RefreshQueueLen = c.refreshQueue.Len()
+	c.mu.Unlock()
+	return out
+}
+
+// ListKeys returns information of all keys in the cache,
+// it is mainly used to build debug pages.
+func (c *TypedCache[K, V]) ListKeys() []KeyInfo[K] {
+	var out []KeyInfo[K]
+	c.data.Range(func(key, val any) bool {
+		ent := val.(*entry[V])
+		data := ent.Load()
+		info := KeyInfo[K]{
+			Key:         key.(K),
+			HasValue:    data.hasVal,
+			IsDefault:   data.err == errDefaultVal,
+			RefreshedAt: time.Unix(0, ent.GetUpdateAt()),
+			Err:         data.fetchErr,
+		}
+		if data.hasVal {
+			info.UpdatedAt = time.Unix(0, data.valAt)
+		}
+		if data.fetchErr != nil {
+			info.ErrorAt = time.Unix(0, data.errAt)
+		}
+		out = append(out, info)
+		return true
+	})
+	return out
+}
+
+func (c *TypedCache[K, V]) exportStats(force bool) {
+	exporter := c.opt.StatsExporter
+	if exporter == nil {
+		return
+	}
+	nowNano := time.Now().UnixNano()
+	preExportAt := c.stats.preExportAt.Load()
+	if !force && time.Duration(nowNano-preExportAt) < c.opt.getStatsExportInterval() {
+		return
+	}
+	if c.stats.preExportAt.CompareAndSwap(preExportAt, nowNano) {
+		exporter.ExportStats(c.Stats())
+	}
+}
@@ -0,0 +1,100 @@
+package acache
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedCache_Stats(t *testing.T) {
+	var exported atomic.Pointer[Stats]
+	opt := TypedOptions[string, int]{
+		Fetcher: TypedFuncFetcher[string, int](func(key string) (int, error) {
+			switch key {
+			case "err":
+				return 0, errors.New("test error")
+			case "notfound":
+				return 0, fmt.Errorf("key %s: %w", key, ErrNotFound)
+			}
+			return len(key), nil
+		}),
+		Name:                "test",
+		StatsExportInterval: 20 * time.Millisecond,
+		StatsExporter: StatsExporterFunc(func(stats Stats) {
+			exported.Store(&stats)
+		}),
+	}
+	c := newTypedCacheWithTickInterval(opt, 10*time.Millisecond)
+	defer c.Close()
+
+	for _, key := range []string{"a", "a", "bb", "err", "err", "notfound"} {
+		_, _ = c.Get(key)
+	}
+	stats := c.Stats()
+	assert.Equal(t, "test", stats.Name)
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(4), stats.Misses)
+	assert.Equal(t, int64(4), stats.Fetches)
+	assert.Equal(t, int64(1), stats.FetchErrors)
+	assert.Equal(t, int64(1), stats.NotFounds)
+	assert.Equal(t, 4, stats.Entries)
+	assert.Equal(t, 4, stats.RefreshQueueLen)
+	assert.Equal(t, int64(4), stats.FetchLatency.Count)
+	assert.Len(t, stats.FetchLatency.Counts, len(stats.FetchLatency.Buckets)+1)
+	assert.Equal(t, int64(4), stats.FetchLatency.Counts[0])
+
+	c.doExpire(true)
+	c.doExpire(true)
+	assert.Equal(t, int64(4), c.Stats().ExpiredDeletes)
+	assert.Equal(t, 0, c.Stats().Entries)
+
+	time.Sleep(50 * time.Millisecond)
+	got := exported.Load()
+	if assert.NotNil(t, got) {
+		assert.Equal(t, "test", got.Name)
+		assert.Equal(t, int64(4), got.Fetches)
+	}
+}
+
+func TestTypedCache_ListKeys(t *testing.T) {
+	opt := TypedOptions[string, int]{
+		Fetcher: TypedFuncFetcher[string, int](func(key string) (int, error) {
+			if key == "err" {
+				return 0, errors.New("test error")
+			}
+			return len(key), nil
+		}),
+	}
+	c := NewTypedCache(opt)
+	defer c.Close()
+
+	_, _ = c.Get("abc")
+	_, _ = c.Get("err")
+	c.SetDefault("default", 1)
+
+	infos := make(map[string]KeyInfo[string])
+	for _, info := range c.ListKeys() {
+		infos[info.Key] = info
+	}
+	assert.Len(t, infos, 3)
+
+	abc := infos["abc"]
+	assert.True(t, abc.HasValue)
+	assert.False(t, abc.IsDefault)
+	assert.False(t, abc.UpdatedAt.IsZero())
+	assert.Nil(t, abc.Err)
+	assert.True(t, abc.ErrorAt.IsZero())
+
+	errInfo := infos["err"]
+	assert.False(t, errInfo.HasValue)
+	assert.True(t, errInfo.UpdatedAt.IsZero())
+	assert.EqualError(t, errInfo.Err, "test error")
+	assert.False(t, errInfo.ErrorAt.IsZero())
+	assert.False(t, errInfo.RefreshedAt.IsZero())
+
+	assert.True(t, infos["default"].IsDefault)
+}
@@ -0,0 +1,724 @@
+package acache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jxskiss/gopkg/v2/collection/heapx"
+	"github.com/jxskiss/gopkg/v2/internal/functicker"
+)
+
+// TypedOptions configures the behavior of TypedCache.
+type TypedOptions[K comparable, V any] struct {
+
+	// Fetcher fetches data from upstream system for a given key.
+	// Result value or error will be cached till next refresh execution.
+	//
+	// A Fetcher returns ErrNotFound (maybe wrapped) to indicate that
+	// the key does not exist in the upstream system, which is different
+	// with a failure, a not found result replaces the cached value
+	// during refresh, while a failure keeps the cached value.
+	//
+	// The returned value from this function should not be changed after
+	// retrieved from the Cache, else data race happens since there may be
+	// many goroutines access the same value concurrently.
+	Fetcher TypedFetcher[K, V]
+
+	// FetchTimeout is used to timeout the fetch request if given,
+	// default is zero (no timeout).
+	//
+	// NOTE: properly configured timeout will prevent task which take very long
+	// time that don't fail fast, which may further block many requests, and
+	// consume huge amount of resources, cause system overload or out of memory.
+	FetchTimeout time.Duration
+
+	// RefreshInterval specifies the interval to refresh the cache values,
+	// default is zero which means don't refresh the cached values.
+	//
+	// If there is valid cache value and the subsequent fetch requests
+	// failed, the existing cache value will be kept untouched.
+	RefreshInterval time.Duration
+
+	// ExpireInterval optionally enables purging unused cached values,
+	// default is zero which means no expiration.
+	//
+	// Note this is mainly used to purge unused data to prevent the cache
+	// growing endlessly, the timing is inaccurate. Also note that
+	// it may delete unused default values set by SetDefault.
+	//
+	// Cached values are deleted using a mark-then-delete strategy.
+	// In each tick of expire interval, an active value will be marked as inactive,
+	// if it's not accessed within the next expire interval, it will be
+	// deleted from the Cache by the next expire execution.
+	//
+	// Each access of the cache value will touch it and mark it as active, which
+	// prevents it being deleted from the cache.
+	ExpireInterval time.Duration
+
+	// MaxStaleness optionally limits how long a cached value can be
+	// served since it was last fetched successfully, default is zero
+	// which means no limit.
+	//
+	// When refreshing keeps failing and a value becomes older than
+	// MaxStaleness, Get returns the stale value together with an error
+	// which wraps ErrStale and the last fetch error, or it blocks to
+	// fetch a fresh value if BlockOnStale is true.
+	// It should be larger than RefreshInterval, else values become stale
+	// before they get a chance to be refreshed.
+	// Default values set by SetDefault and GetOrDefault never become stale.
+	MaxStaleness time.Duration
+
+	// BlockOnStale tells Get to block and fetch a fresh value from
+	// upstream when the cached value exceeds MaxStaleness.
+	// If the fetch fails, the stale value and an error which wraps
+	// ErrStale and the fetch error are returned.
+	// The fetch is bounded by FetchTimeout if it is given, a timeout
+	// is reported as a fetch error which wraps ErrFetchTimeout.
+	BlockOnStale bool
+
+	// ErrorTTL optionally specifies how long an error returned by
+	// Fetcher is cached, after that, the next Get triggers a new fetch.
+	// Default is zero which means the error is cached until it is
+	// replaced by a subsequent refresh.
+	//
+	// Note that an error returned during refresh does not replace
+	// an existing valid value, see RefreshInterval.
+	ErrorTTL time.Duration
+
+	// NotFoundTTL optionally specifies how long a not found result
+	// (an error which wraps ErrNotFound) is cached,
+	// the default is same with ErrorTTL.
+	NotFoundTTL time.Duration
+
+	// ErrorCallback is an optional callback which will be called when
+	// an error is returned by Fetcher during refresh.
+	// Not found results (errors which wrap ErrNotFound) are not
+	// considered as failures, thus won't trigger ErrorCallback.
+	ErrorCallback func(err error, keys []K)
+
+	// ChangeCallback is an optional callback which will be called when
+	// new value is returned by Fetcher during refresh.
+	ChangeCallback func(key K, oldData, newData V)
+
+	// DeleteCallback is an optional callback which will be called when
+	// a value is deleted from the cache.
+	DeleteCallback func(key K, data V)
+
+	// Name optionally names the cache, it is reported in Stats.
+	Name string
+
+	// StatsExporter optionally receives statistics of the cache
+	// periodically, see StatsExportInterval.
+	StatsExporter StatsExporter
+
+	// StatsExportInterval specifies the interval to call StatsExporter,
+	// default is one minute.
+	StatsExportInterval time.Duration
+
+	// MaxEntries optionally limits the number of entries in the cache,
+	// default is zero which means no limit.
+	//
+	// When the cache exceeds the limit, entries are evicted according
+	// to EvictionPolicy, and DeleteCallback is called for evicted values.
+	// Unlike ExpireInterval which purges unused entries periodically,
+	// it bounds the cache size all the time.
+	MaxEntries int
+
+	// MaxCost optionally limits the total cost of entries in the cache,
+	// the cost of an entry is calculated by CostFunc.
+	// Default is zero which means no limit.
+	MaxCost int64
+
+	// CostFunc optionally calculates the cost of a value, it is used
+	// with MaxCost. If it is nil, or an entry has no value (e.g. a cached
+	// error), the cost of the entry is 1.
+	CostFunc func(key K, value V) int64
+
+	// EvictionPolicy specifies which entries are evicted when the cache
+	// exceeds MaxEntries or MaxCost, default is EvictLRU.
+	EvictionPolicy EvictionPolicy
+}
+
+func (p *TypedOptions[K, V]) validate() {
+	if p.Fetcher == nil {
+		panic("acache: Options.Fetcher must not be nil")
+	}
+}
+
+func (p *TypedOptions[K, V]) getStatsExportInterval() time.Duration {
+	if p.StatsExportInterval > 0 {
+		return p.StatsExportInterval
+	}
+	return time.Minute
+}
+
+// TypedCache is a generic version of Cache, it stores values of type V
+// for keys of type K, thus no type assertion is needed by the caller.
+//
+// Zero value of TypedCache is not ready to use. Use the function
+// NewTypedCache to make a new TypedCache instance. A TypedCache value
+// shall not be copied after initialized.
+type TypedCache[K comparable, V any] struct {
+	opt     TypedOptions[K, V]
+	sfGroup sfGroup[K, V]
+	data    sync.Map // K -> *entry[V]
+	stats   cacheStats
+
+	// evictor is nil if neither MaxEntries nor MaxCost is set.
+	evictMu  sync.Mutex
+	evictor  evictor[K]
+	accesses *accessBuffer[K]
+
+	mu           sync.Mutex
+	refreshQueue *heapx.PriorityQueue[int64, K]
+
+	ticker       *functicker.Ticker
+	preExpireAt  atomic.Int64
+	doingExpire  int32
+	doingRefresh int32
+	closed       int32
+}
+
+// NewTypedCache returns a new TypedCache instance using the given options.
+func NewTypedCache[K comparable, V any](opt TypedOptions[K, V]) *TypedCache[K, V] {
+	tickInterval := time.Second
+	return newTypedCacheWithTickInterval(opt, tickInterval)
+}
+
+func newTypedCacheWithTickInterval[K comparable, V any](opt TypedOptions[K, V], tickInterval time.Duration) *TypedCache[K, V] {
+	opt.validate()
+	c := &TypedCache[K, V]{
+		opt:          opt,
+		refreshQueue: heapx.NewMinPriorityQueue[int64, K](),
+	}
+	if opt.MaxEntries > 0 || opt.MaxCost > 0 {
+		c.evictor = newEvictor[K](opt.EvictionPolicy)
+		c.accesses = &accessBuffer[K]{}
+	}
+	if opt.ExpireInterval > 0 || opt.RefreshInterval > 0 || opt.StatsExporter != nil {
+		c.stats.preExportAt.Store(time.Now().UnixNano())
+		c.ticker = functicker.New(tickInterval, c.runBackgroundTasks)
+	}
+	return c
+}
+
+// Close closes the TypedCache.
+// It signals the background goroutines to shut down.
+//
+// It should be called when the TypedCache is no longer needed,
+// or may lead resource leaks.
+func (c *TypedCache[K, V]) Close() {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return
+	}
+	if c.ticker != nil {
+		c.ticker.Stop()
+		c.ticker = nil
+	}
+}
+
+// SetDefault sets the default value of a given key if it is new to the cache.
+// The returned bool value indicates whether the key already exists in the cache,
+// if it already exists, this is a no-op.
+//
+// It's useful to warm up the cache.
+func (c *TypedCache[K, V]) SetDefault(key K, value V) (exists bool) {
+	nowNano := time.Now().UnixNano()
+	ent := newEntry(&entryData[V]{val: value, hasVal: true, err: errDefaultVal, valAt: nowNano}, nowNano)
+	actual, loaded := c.loadOrStoreEntry(key, ent)
+	if loaded {
+		actual.MarkActive()
+	} else {
+		c.addToRefreshQueue(nowNano, key)
+	}
+	return loaded
+}
+
+// Update sets a value for key into the cache.
+// If key is not cached in the cache, it adds the given key value to the cache.
+func (c *TypedCache[K, V]) Update(key K, value V) {
+	nowNano := time.Now().UnixNano()
+	c.updateEntry(key, value, nowNano)
+	c.addToRefreshQueue(nowNano, key)
+}
+
+// Contains tells whether the cache contains the specified key.
+// It returns false if key is never accessed from the cache,
+// true means that a value or an error for key exists in the cache.
+func (c *TypedCache[K, V]) Contains(key K) bool {
+	_, ok := c.data.Load(key)
+	return ok
+}
+
+// Get tries to fetch a value corresponding to the given key from the cache.
+// If it's not cached, a calling to Fetcher.Fetch will be fired
+// and the result will be cached.
+//
+// If error occurs during the first fetching, the error will be cached until
+// the subsequent fetching requests triggered by refreshing succeed,
+// or Options.ErrorTTL passed.
+// The cached error will be returned, it does not trigger a calling to
+// Options.Fetcher again.
+//
+// If a default value is set by SetDefault, the default value will be used,
+// it does not trigger a calling to Options.Fetcher.
+//
+// If the cached value exceeds Options.MaxStaleness, the stale value
+// is returned with an error which wraps ErrStale,
+// see Options.MaxStaleness and Options.BlockOnStale for details.
+func (c *TypedCache[K, V]) Get(key K) (V, error) {
+	var zero V
+	value, err := c.get(key, zero, false)
+	if err == errDefaultVal {
+		err = nil
+	}
+	return value, err
+}
+
+// GetOrDefault tries to fetch a value corresponding to the given key from
+// the cache. If it's not cached, a calling to Options.Fetcher
+// will be fired and the result will be cached.
+//
+// If error occurs during the first fetching, defaultVal will be set into
+// the cache and returned, the default value will also be used for
+// further calling of Get and GetOrDefault.
+func (c *TypedCache[K, V]) GetOrDefault(key K, defaultVal V) V {
+	value, err := c.get(key, defaultVal, true)
+	if err != nil {
+		value = defaultVal
+	}
+	return value
+}
+
+func (c *TypedCache[K, V]) get(key K, defaultVal V, hasDefault bool) (V, error) {
+	val, ok := c.data.Load(key)
+	if !ok {
+		// Wait the fetch function to get result.
+		c.stats.misses.Add(1)
+		return c.doFetch(key, defaultVal, hasDefault)
+	}
+
+	ent := val.(*entry[V])
+	ent.MarkActive()
+	c.touchEntry(key)
+	data := ent.Load()
+	if c.opt.ErrorTTL > 0 || c.opt.NotFoundTTL > 0 || c.opt.MaxStaleness > 0 {
+		nowNano := time.Now().UnixNano()
+		if c.isErrorExpired(data, nowNano) {
+			c.stats.misses.Add(1)
+			return c.doFetch(key, defaultVal, hasDefault)
+		}
+		if c.isStale(data, nowNano) {
+			if c.opt.BlockOnStale {
+				c.stats.misses.Add(1)
+				return c.refetchStale(key, ent)
+			}
+			c.stats.hits.Add(1)
+			return data.val, staleError(data.fetchErr)
+		}
+	}
+	c.stats.hits.Add(1)
+	return data.val, data.err
+}
+
+func (c *TypedCache[K, V]) isErrorExpired(data *entryData[V], nowNano int64) bool {
+	if data.hasVal || data.err == nil {
+		return false
+	}
+	ttl := c.opt.ErrorTTL
+	if c.opt.NotFoundTTL > 0 && errors.Is(data.err, ErrNotFound) {
+		ttl = c.opt.NotFoundTTL
+	}
+	return ttl > 0 && time.Duration(nowNano-data.errAt) >= ttl
+}
+
+func (c *TypedCache[K, V]) isStale(data *entryData[V], nowNano int64) bool {
+	return c.opt.MaxStaleness > 0 &&
+		data.hasVal && data.err != errDefaultVal &&
+		time.Duration(nowNano-data.valAt) > c.opt.MaxStaleness
+}
+
+// refetchStale fetches a fresh value for a stale entry,
+// the stale value is kept if the fetch fails or times out.
+func (c *TypedCache[K, V]) refetchStale(key K, ent *entry[V]) (V, error) {
+	val, err := c.singleflight(key, func() (V, error) {
+		nowNano := time.Now().UnixNano()
+		newVal, err := c.fetch(key)
+		if err != nil {
+			ent.SetError(err, nowNano)
+			c.updateEntryCost(key, ent)
+			data := ent.Load()
+			if data.hasVal {
+				return data.val, staleError(err)
+			}
+			return data.val, data.err
+		}
+		if c.opt.ChangeCallback != nil {
+			c.opt.ChangeCallback(key, ent.Load().val, newVal)
+		}
+		ent.SetValue(newVal, nowNano)
+		c.updateEntryCost(key, ent)
+		c.addToRefreshQueue(nowNano, key)
+		return newVal, nil
+	})
+	if err == ErrFetchTimeout {
+		if data := ent.Load(); data.hasVal {
+			return data.val, staleError(err)
+		}
+	}
+	return val, err
+}
+
+// fetch calls Fetcher to fetch value for key and records the statistics.
+func (c *TypedCache[K, V]) fetch(key K) (V, error) {
+	start := time.Now()
+	val, err := c.opt.Fetcher.Fetch(key)
+	c.stats.observeFetch(time.Since(start), err)
+	return val, err
+}
+
+func staleError(fetchErr error) error {
+	if fetchErr == nil {
+		return ErrStale
+	}
+	return fmt.Errorf("%w: %w", ErrStale, fetchErr)
+}
+
+func (c *TypedCache[K, V]) doFetch(key K, defaultVal V, hasDefault bool) (V, error) {
+	fetch := func() (V, error) {
+		val, err := c.fetch(key)
+		nowNano := time.Now().UnixNano()
+		data := &entryData[V]{val: val, hasVal: true, valAt: nowNano}
+		if err != nil {
+			data = &entryData[V]{err: err, fetchErr: err, errAt: nowNano}
+			if hasDefault && !errors.Is(err, ErrNotFound) {
+				data.val, data.hasVal, data.err, data.valAt = defaultVal, true, errDefaultVal, nowNano
+			}
+			val, err = data.val, data.err
+		}
+		c.storeEntry(key, newEntry(data, nowNano))
+		c.addToRefreshQueue(nowNano, key)
+		return val, err
+	}
+	return c.singleflight(key, fetch)
+}
+
+// singleflight calls fn with duplicate calls for key suppressed,
+// it returns ErrFetchTimeout if Options.FetchTimeout is given and
+// fn does not return in time.
+func (c *TypedCache[K, V]) singleflight(key K, fetch func() (V, error)) (V, error) {
+	if c.opt.FetchTimeout == 0 {
+		return c.sfGroup.Do(key, fetch)
+	}
+
+	timeout := time.NewTimer(c.opt.FetchTimeout)
+	ch := c.sfGroup.DoChan(key, fetch)
+	select {
+	case <-timeout.C:
+		var zero V
+		return zero, ErrFetchTimeout
+	case result := <-ch:
+		timeout.Stop()
+		return result.Val, result.Err
+	}
+}
+
+// Delete deletes the entry of key from the cache if it exists.
+func (c *TypedCache[K, V]) Delete(key K) {
+	if ent, ok := c.deleteEntry(key, nil); ok {
+		c.onDelete(key, ent)
+	}
+}
+
+// DeleteFunc iterates the cache and deletes entries that the key matches
+// the given function.
+func (c *TypedCache[K, V]) DeleteFunc(match func(key K) bool) {
+	c.data.Range(func(key, val any) bool {
+		k := key.(K)
+		if match(k) {
+			if ent, deleted := c.deleteEntry(k, nil); deleted {
+				c.onDelete(k, ent)
+			}
+		}
+		return true
+	})
+}
+
+func (c *TypedCache[K, V]) onDelete(key K, ent *entry[V]) {
+	if c.opt.DeleteCallback == nil {
+		return
+	}
+	if data := ent.Load(); data.hasVal {
+		c.opt.DeleteCallback(key, data.val)
+	}
+}
+
+func (c *TypedCache[K, V]) addToRefreshQueue(updateAtNano int64, key K) {
+	c.mu.Lock()
+	c.refreshQueue.Push(updateAtNano, key)
+	c.mu.Unlock()
+}
+
+func (c *TypedCache[K, V]) runBackgroundTasks() {
+	if atomic.LoadInt32(&c.closed) > 0 {
+		return
+	}
+	if c.opt.ExpireInterval > 0 {
+		c.doExpire(false)
+	}
+	if c.opt.RefreshInterval > 0 {
+		if atomic.CompareAndSwapInt32(&c.doingRefresh, 0, 1) {
+			c.doRefresh()
+			atomic.StoreInt32(&c.doingRefresh, 0)
+		}
+	}
+	c.exportStats(false)
+}
+
+func (c *TypedCache[K, V]) doExpire(force bool) {
+	nowUnix := time.Now().Unix()
+	preExpireAt := c.preExpireAt.Load()
+
+	// "force" helps to do unittest.
+	if !force {
+		if preExpireAt == 0 {
+			c.preExpireAt.Store(nowUnix)
+			return
+		}
+		if time.Duration(nowUnix-preExpireAt)*time.Second < c.opt.ExpireInterval {
+			return
+		}
+	}
+
+	if atomic.CompareAndSwapInt32(&c.doingExpire, 0, 1) {
+		defer atomic.StoreInt32(&c.doingExpire, 0)
+		c.preExpireAt.Store(nowUnix)
+		c.data.Range(func(key, val any) bool {
+			ent := val.(*entry[V])
+
+			// If entry.expire is "active", we mark it as "inactive" here.
+			// Then during the next execution, "inactive" entries will be deleted.
+			isActive := atomic.CompareAndSwapInt32(&ent.expire, active, inactive)
+			if !isActive {
+				if _, deleted := c.deleteEntry(key.(K), ent); deleted {
+					c.stats.expiredDeletes.Add(1)
+					c.onDelete(key.(K), ent)
+				}
+			}
+			return true
+		})
+	}
+}
+
+func (c *TypedCache[K, V]) needRefresh(nowNano, updateAtNano int64) bool {
+	return time.Duration(nowNano-updateAtNano) >= c.opt.RefreshInterval
+}
+
+func (c *TypedCache[K, V]) checkEntryNeedRefresh(nowNano, updateAtNano int64, key K) (ent *entry[V], refresh bool) {
+	val, _ := c.data.Load(key)
+	if val == nil {
+		// The data has already been deleted.
+		return nil, false
+	}
+	ent = val.(*entry[V])
+	if ent.GetUpdateAt() != updateAtNano {
+		// The data has already been changed.
+		return ent, false
+	}
+	if !c.needRefresh(nowNano, updateAtNano) {
+		return ent, false
+	}
+	return ent, true
+}
+
+func (c *TypedCache[K, V]) doRefresh() {
+	if _, ok := c.opt.Fetcher.(TypedBatchFetcher[K, V]); ok {
+		c.doBatchRefresh()
+		return
+	}
+
+	hasErrorCallback := c.opt.ErrorCallback != nil
+	hasChangeCallback := c.opt.ChangeCallback != nil
+	for {
+		nowNano := time.Now().UnixNano()
+		needRefresh := false
+
+		c.mu.Lock()
+		updateAt, key, ok := c.refreshQueue.Peek()
+		if ok && c.needRefresh(nowNano, updateAt) {
+			c.refreshQueue.Pop()
+			needRefresh = true
+		}
+		c.mu.Unlock()
+		if !needRefresh {
+			break
+		}
+
+		var ent *entry[V]
+		ent, needRefresh = c.checkEntryNeedRefresh(nowNano, updateAt, key)
+		if !needRefresh {
+			continue
+		}
+		newVal, err := c.fetch(key)
+		if err != nil {
+			if hasErrorCallback && !errors.Is(err, ErrNotFound) {
+				c.opt.ErrorCallback(err, []K{key})
+			}
+			ent.SetError(err, nowNano)
+		} else {
+			// Save the new value from upstream.
+			if hasChangeCallback {
+				c.opt.ChangeCallback(key, ent.Load().val, newVal)
+			}
+			ent.SetValue(newVal, nowNano)
+		}
+		c.updateEntryCost(key, ent)
+		c.addToRefreshQueue(nowNano, key)
+	}
+}
+
+type refreshQueueItem[K comparable] struct {
+	key   K
+	tNano int64
+}
+
+func (c *TypedCache[K, V]) doBatchRefresh() {
+	fetcher := c.opt.Fetcher.(TypedBatchFetcher[K, V])
+	batchSize := fetcher.BatchSize()
+	expiredItems := make([]refreshQueueItem[K], 0, batchSize)
+	keys := make([]K, 0, batchSize)
+	for {
+		nowNano := time.Now().UnixNano()
+		expiredItems = expiredItems[:0]
+		keys = keys[:0]
+
+		c.mu.Lock()
+		for len(expiredItems) < batchSize {
+			updateAt, key, ok := c.refreshQueue.Peek()
+			if !ok || !c.needRefresh(nowNano, updateAt) {
+				break
+			}
+			c.refreshQueue.Pop()
+			expiredItems = append(expiredItems, refreshQueueItem[K]{key, updateAt})
+		}
+		c.mu.Unlock()
+
+		for _, item := range expiredItems {
+			_, needRefresh := c.checkEntryNeedRefresh(nowNano, item.tNano, item.key)
+			if !needRefresh {
+				continue
+			}
+			keys = append(keys, item.key)
+		}
+		if len(keys) > 0 {
+			c.batchRefreshKeys(keys)
+		}
+
+		// No more expired data to refresh.
+		if len(expiredItems) < batchSize {
+			break
+		}
+	}
+}
+
+func (c *TypedCache[K, V]) batchRefreshKeys(keys []K) {
+	nowNano := time.Now().UnixNano()
+	fetcher := c.opt.Fetcher.(TypedBatchFetcher[K, V])
+	start := time.Now()
+	newValMap, err := fetcher.BatchFetch(keys)
+	c.stats.observeFetch(time.Since(start), err)
+	if err != nil {
+		hasErrorCallback := c.opt.ErrorCallback != nil
+		if hasErrorCallback && !errors.Is(err, ErrNotFound) {
+			c.opt.ErrorCallback(err, keys)
+		}
+	}
+	hasChangeCallback := c.opt.ChangeCallback != nil
+	for _, key := range keys {
+		entVal, _ := c.data.Load(key)
+		if entVal == nil {
+			continue
+		}
+		ent := entVal.(*entry[V])
+		if err != nil {
+			ent.SetError(err, nowNano)
+		} else if newVal, ok := newValMap[key]; ok {
+			if hasChangeCallback {
+				c.opt.ChangeCallback(key, ent.Load().val, newVal)
+			}
+			ent.SetValue(newVal, nowNano)
+		} else {
+			// Keys missing from the result are kept untouched,
+			// and will be retried in the next refresh.
+			ent.SetUpdateAt(nowNano)
+		}
+		c.updateEntryCost(key, ent)
+		c.addToRefreshQueue(nowNano, key)
+	}
+}
+
+func newEntry[V any](data *entryData[V], updateAtNano int64) *entry[V] {
+	ent := &entry[V]{}
+	ent.data.Store(data)
+	ent.SetUpdateAt(updateAtNano)
+	return ent
+}
+
+type entry[V any] struct {
+	data     atomic.Pointer[entryData[V]]
+	updateAt int64
+	expire   int32
+}
+
+type entryData[V any] struct {
+	val    V
+	hasVal bool
+	err    error // the error returned to caller, errDefaultVal for default values
+	valAt  int64 // the time when val is stored
+
+	fetchErr error // the last error returned by Fetcher
+	errAt    int64 // the time when fetchErr occurs
+}
+
+func (e *entry[V]) Load() *entryData[V] {
+	return e.data.Load()
+}
+
+// SetValue stores a new value to the entry, it clears the error.
+func (e *entry[V]) SetValue(val V, nowNano int64) {
+	e.data.Store(&entryData[V]{val: val, hasVal: true, valAt: nowNano})
+	e.SetUpdateAt(nowNano)
+}
+
+// SetError records an error returned by Fetcher.
+// If the entry has a valid value and err is not a not found error,
+// the value is kept untouched.
+func (e *entry[V]) SetError(err error, nowNano int64) {
+	old := e.data.Load()
+	data := &entryData[V]{err: err, fetchErr: err, errAt: nowNano}
+	if old.hasVal && !errors.Is(err, ErrNotFound) {
+		data.val, data.hasVal, data.err, data.valAt = old.val, true, old.err, old.valAt
+	}
+	e.data.Store(data)
+	e.SetUpdateAt(nowNano)
+}
+
+func (e *entry[V]) GetUpdateAt() int64 {
+	return atomic.LoadInt64(&e.updateAt)
+}
+
+func (e *entry[V]) SetUpdateAt(updateAtNano int64) {
+	atomic.StoreInt64(&e.updateAt, updateAtNano)
+}
+
+func (e *entry[V]) MarkActive() {
+	atomic.StoreInt32(&e.expire, active)
+}
+
+const (
+	active   = 0
+	inactive = 1
+)
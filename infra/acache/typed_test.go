@@ -0,0 +1,195 @@
+package acache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testTypedKey struct {
+	Tenant string
+	ID     int64
+}
+
+type testTypedValue struct {
+	Name    string
+	Version int
+}
+
+func TestTypedCache(t *testing.T) {
+	var version atomic.Int64
+	var fetchCount atomic.Int64
+	opt := TypedOptions[testTypedKey, *testTypedValue]{
+		RefreshInterval: 50 * time.Millisecond,
+		Fetcher: TypedFuncFetcher[testTypedKey, *testTypedValue](func(key testTypedKey) (*testTypedValue, error) {
+			fetchCount.Add(1)
+			time.Sleep(10 * time.Millisecond)
+			if key.ID < 0 {
+				return nil, errors.New("invalid id")
+			}
+			return &testTypedValue{Name: key.Tenant, Version: int(version.Load())}, nil
+		}),
+	}
+	c := newTypedCacheWithTickInterval(opt, 10*time.Millisecond)
+	defer c.Close()
+
+	key := testTypedKey{Tenant: "t1", ID: 1}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := c.Get(key)
+			assert.Nil(t, err)
+			assert.Equal(t, "t1", got.Name)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1), fetchCount.Load())
+	assert.True(t, c.Contains(key))
+
+	version.Store(1)
+	time.Sleep(opt.RefreshInterval + 50*time.Millisecond)
+	got, err := c.Get(key)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, got.Version)
+
+	got, err = c.Get(testTypedKey{Tenant: "t1", ID: -1})
+	assert.NotNil(t, err)
+	assert.Nil(t, got)
+
+	defaultVal := &testTypedValue{Name: "default"}
+	got = c.GetOrDefault(testTypedKey{Tenant: "t2", ID: -1}, defaultVal)
+	assert.Equal(t, defaultVal, got)
+}
+
+func TestTypedCache_ZeroValue(t *testing.T) {
+	opt := TypedOptions[string, int]{
+		Fetcher: TypedFuncFetcher[string, int](func(key string) (int, error) {
+			return 0, errors.New("error")
+		}),
+	}
+	c := NewTypedCache(opt)
+	defer c.Close()
+
+	// Zero value is a valid value for TypedCache.
+	c.SetDefault("a", 0)
+	got, err := c.Get("a")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, got)
+
+	c.Update("b", 0)
+	got, err = c.Get("b")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, got)
+}
+
+type testTypedBatchFetcher struct {
+	calls atomic.Int64
+}
+
+func (f *testTypedBatchFetcher) Fetch(key int) (string, error) {
+	return "single", nil
+}
+
+func (f *testTypedBatchFetcher) BatchSize() int { return 2 }
+
+func (f *testTypedBatchFetcher) BatchFetch(keys []int) (map[int]string, error) {
+	f.calls.Add(1)
+	out := make(map[int]string, len(keys))
+	for _, k := range keys {
+		out[k] = "batch"
+	}
+	return out, nil
+}
+
+func TestTypedCache_BatchRefresh(t *testing.T) {
+	fetcher := &testTypedBatchFetcher{}
+	var changed atomic.Int64
+	opt := TypedOptions[int, string]{
+		Fetcher:         fetcher,
+		RefreshInterval: 50 * time.Millisecond,
+		ChangeCallback: func(key int, oldData, newData string) {
+			assert.Equal(t, "single", oldData)
+			assert.Equal(t, "batch", newData)
+			changed.Add(1)
+		},
+	}
+	c := newTypedCacheWithTickInterval(opt, 10*time.Millisecond)
+	defer c.Close()
+
+	for i := 0; i < 5; i++ {
+		got, err := c.Get(i)
+		assert.Nil(t, err)
+		assert.Equal(t, "single", got)
+	}
+	time.Sleep(opt.RefreshInterval + 30*time.Millisecond)
+	for i := 0; i < 5; i++ {
+		got, err := c.Get(i)
+		assert.Nil(t, err)
+		assert.Equal(t, "batch", got)
+	}
+	assert.GreaterOrEqual(t, fetcher.calls.Load(), int64(3))
+	assert.Equal(t, int64(5), changed.Load())
+}
+
+func TestTypedCache_DeleteCallback(t *testing.T) {
+	deleted := make(map[string]int)
+	opt := TypedOptions[string, int]{
+		Fetcher: TypedFuncFetcher[string, int](func(key string) (int, error) {
+			return len(key), nil
+		}),
+		DeleteCallback: func(key string, data int) {
+			deleted[key] = data
+		},
+	}
+	c := NewTypedCache(opt)
+	defer c.Close()
+
+	for _, key := range []string{"a", "bb", "ccc"} {
+		_, _ = c.Get(key)
+	}
+	c.Delete("a")
+	c.DeleteFunc(func(key string) bool { return len(key) == 2 })
+	assert.Equal(t, map[string]int{"a": 1, "bb": 2}, deleted)
+
+	c.doExpire(true)
+	c.doExpire(true)
+	assert.Equal(t, map[string]int{"a": 1, "bb": 2, "ccc": 3}, deleted)
+	assert.False(t, c.Contains("ccc"))
+}
+
+func TestSingleflightPanic(t *testing.T) {
+	var g sfGroup[string, int]
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		defer func() { recover() }()
+		g.Do("k", func() (int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	ch := g.DoChan("k", func() (int, error) { return 1, nil })
+	close(release)
+	res := <-ch
+	var panicErr *sfPanicError
+	assert.ErrorAs(t, res.Err, &panicErr)
+	assert.Equal(t, 0, res.Val)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		g.Do("k", func() (int, error) { panic("boom") })
+	})
+
+	// A panic in DoChan is recovered and returned as an error.
+	res = <-g.DoChan("k", func() (int, error) { panic("boom") })
+	assert.ErrorAs(t, res.Err, &panicErr)
+}
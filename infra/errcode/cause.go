@@ -0,0 +1,106 @@
+package errcode
+
+import (
+	"log/slog"
+	"runtime"
+
+	"github.com/jxskiss/gopkg/v2/internal"
+)
+
+// WithStack returns an option to make a Registry capture stack traces
+// when deriving new Codes by WithCause, Wrap, AddDetails or WithMessage.
+// The stack is printed by "%+v" formatting and logged by LogValue.
+//
+// Codes returned by Register and RegisterReserved never have stack
+// traces, since they are typically created during initialization.
+func WithStack() Option {
+	return Option{
+		applyRegistry: func(r *Registry) {
+			r.withStack = true
+		},
+	}
+}
+
+const maxStackDepth = 32
+
+// captureStack records the stack trace if the registry enables it,
+// skip 0 identifies the caller of the method which calls captureStack.
+func (e *Code) captureStack(skip int) {
+	if e.stack != nil || e.reg == nil || !e.reg.withStack {
+		return
+	}
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(skip+3, pcs[:])
+	e.stack = append([]uintptr(nil), pcs[:n]...)
+}
+
+// WithCause returns a copy of Code with the underlying error cause
+// attached, the cause can be retrieved by Unwrap.
+//
+// Note that the cause is not included in the result of Error,
+// thus it is not exposed to end-users, it is printed by "%+v"
+// formatting and logged by LogValue.
+func (e *Code) WithCause(err error) (code *Code) {
+	code = e.clone()
+	code.cause = err
+	code.captureStack(0)
+	return
+}
+
+// Wrap returns a copy of code with err attached as the cause,
+// see Code.WithCause. It returns nil if err is nil.
+func Wrap(err error, code *Code) *Code {
+	if err == nil {
+		return nil
+	}
+	ret := code.clone()
+	ret.cause = err
+	ret.captureStack(0)
+	return ret
+}
+
+// Cause returns the underlying error cause attached by WithCause or Wrap.
+func (e *Code) Cause() error { return e.cause }
+
+// Unwrap returns the underlying error cause attached by WithCause or Wrap,
+// it makes errors.Is and errors.As work with the cause.
+func (e *Code) Unwrap() error { return e.cause }
+
+// StackTrace returns the stack frames captured when the Code is derived,
+// it returns nil if the registry does not enable stack capture,
+// see WithStack.
+func (e *Code) StackTrace() []runtime.Frame {
+	if len(e.stack) == 0 {
+		return nil
+	}
+	frames := make([]runtime.Frame, 0, len(e.stack))
+	callerFrames := runtime.CallersFrames(e.stack)
+	for {
+		f, more := callerFrames.Next()
+		frames = append(frames, f)
+		if !more {
+			break
+		}
+	}
+	return frames
+}
+
+// LogValue implements slog.LogValuer, it renders the code, message,
+// details, cause and stack trace as structured attributes.
+func (e *Code) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, 5)
+	attrs = append(attrs,
+		slog.Int64("code", int64(e.Code())),
+		slog.String("message", e.Message()),
+	)
+	if len(e.details) > 0 {
+		attrs = append(attrs, slog.Any("details", e.details))
+	}
+	if e.cause != nil {
+		attrs = append(attrs, slog.String("cause", e.cause.Error()))
+	}
+	if frames := e.StackTrace(); len(frames) > 0 {
+		attrs = append(attrs, slog.String("stack", string(internal.FormatFrames(frames))))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,69 @@
+package errcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCause(t *testing.T) {
+	reg := New()
+	dbErr := reg.Register(1003, "database error")
+
+	err := func() error {
+		return fmt.Errorf("query user: %w", dbErr.WithCause(io.ErrUnexpectedEOF))
+	}()
+	assert.True(t, errors.Is(err, dbErr))
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+	assert.True(t, Is(err, dbErr))
+	assert.Equal(t, "query user: [1003] database error", err.Error())
+
+	var code *Code
+	require.True(t, errors.As(err, &code))
+	assert.Equal(t, io.ErrUnexpectedEOF, code.Cause())
+	assert.Nil(t, code.StackTrace())
+	assert.Equal(t, "[1003] database error; Cause: unexpected EOF", fmt.Sprintf("%+v", code))
+	assert.Nil(t, code.RemoveDetails().Cause())
+
+	assert.Nil(t, Wrap(nil, dbErr))
+	wrapped := Wrap(io.EOF, dbErr.AddDetails("user_id", 1))
+	assert.True(t, errors.Is(wrapped, dbErr))
+	assert.True(t, errors.Is(wrapped, io.EOF))
+	assert.Equal(t, []any{"user_id", 1}, wrapped.Details())
+	assert.Nil(t, dbErr.Cause())
+}
+
+func TestWithStack(t *testing.T) {
+	reg := New(WithStack())
+	dbErr := reg.Register(1003, "database error")
+	assert.Nil(t, dbErr.StackTrace())
+
+	err := Wrap(io.EOF, dbErr)
+	frames := err.StackTrace()
+	require.NotEmpty(t, frames)
+	assert.True(t, strings.HasSuffix(frames[0].Function, "TestWithStack"), frames[0].Function)
+	assert.Equal(t, frames[0], err.AddDetails("x").StackTrace()[0])
+
+	frames = dbErr.WithCause(io.EOF).StackTrace()
+	assert.True(t, strings.HasSuffix(frames[0].Function, "TestWithStack"), frames[0].Function)
+	assert.Contains(t, fmt.Sprintf("%+v", err), "cause_test.go:")
+	assert.Nil(t, err.RemoveDetails().StackTrace())
+}
+
+func TestLogValue(t *testing.T) {
+	reg := New()
+	dbErr := reg.Register(1003, "database error")
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	logger.Info("request failed", "error", Wrap(io.EOF, dbErr.AddDetails("user")))
+	got := buf.String()
+	assert.Contains(t, got, `error.code=1003 error.message="database error" error.details=[user] error.cause=EOF`)
+}
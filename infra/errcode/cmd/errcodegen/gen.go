@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+type Catalog struct {
+	Registry string  `yaml:"registry" toml:"registry" json:"-"`
+	Codes    []*Item `yaml:"codes" toml:"codes" json:"codes"`
+}
+
+type Item struct {
+	Code        int32  `yaml:"code" toml:"code" json:"code"`
+	Name        string `yaml:"name" toml:"name" json:"name"`
+	Message     string `yaml:"message" toml:"message" json:"message"`
+	HTTPStatus  int    `yaml:"http_status" toml:"http_status" json:"httpStatus,omitempty"`
+	Description string `yaml:"description" toml:"description" json:"description,omitempty"`
+}
+
+func loadCatalog(filename string) (*Catalog, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read catalog: %w", err)
+	}
+	return parseCatalog(data, strings.TrimPrefix(filepath.Ext(filename), "."))
+}
+
+func parseCatalog(data []byte, format string) (*Catalog, error) {
+	catalog := &Catalog{}
+	var err error
+	switch strings.ToLower(format) {
+	case "yaml", "yml":
+		err = yaml.Unmarshal(data, catalog)
+	case "toml":
+		err = toml.Unmarshal(data, catalog)
+	default:
+		return nil, fmt.Errorf("unsupported catalog format: %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot unmarshal catalog: %w", err)
+	}
+	return catalog, nil
+}
+
+func (c *Catalog) validate(requireDocs bool) error {
+	var errs []error
+	if c.Registry != "" && !token.IsIdentifier(c.Registry) {
+		errs = append(errs, fmt.Errorf("invalid registry name %q", c.Registry))
+	}
+	codes := make(map[int32]string, len(c.Codes))
+	names := make(map[string]int32, len(c.Codes))
+	for _, x := range c.Codes {
+		if prev, ok := codes[x.Code]; ok {
+			errs = append(errs, fmt.Errorf("code %d: duplicate with %s", x.Code, prev))
+		}
+		codes[x.Code] = x.Name
+		if !token.IsIdentifier(x.Name) || !token.IsExported(x.Name) {
+			errs = append(errs, fmt.Errorf("code %d: invalid name %q, must be an exported Go identifier", x.Code, x.Name))
+		} else if prev, ok := names[x.Name]; ok {
+			errs = append(errs, fmt.Errorf("code %d: name %s is already used by code %d", x.Code, x.Name, prev))
+		}
+		names[x.Name] = x.Code
+		if x.Message == "" {
+			errs = append(errs, fmt.Errorf("code %d: missing message", x.Code))
+		}
+		if x.HTTPStatus != 0 && http.StatusText(x.HTTPStatus) == "" {
+			errs = append(errs, fmt.Errorf("code %d: invalid http status %d", x.Code, x.HTTPStatus))
+		}
+		if requireDocs && x.Description == "" {
+			errs = append(errs, fmt.Errorf("code %d: missing description", x.Code))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Catalog) generateGo(pkg, source string) ([]byte, error) {
+	registry := c.Registry
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by errcodegen from %s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	if registry == "" {
+		registry = "Registry"
+		buf.WriteString("import \"github.com/jxskiss/gopkg/v2/infra/errcode\"\n\n")
+		buf.WriteString("// Registry is the registry of error codes generated from the catalog.\n")
+		buf.WriteString("var Registry = errcode.New()\n\n")
+	}
+	buf.WriteString("var (\n")
+	for _, x := range c.Codes {
+		if x.Description != "" {
+			for _, line := range strings.Split(strings.TrimSpace(x.Description), "\n") {
+				fmt.Fprintf(&buf, "\t// %s\n", strings.TrimSpace(line))
+			}
+		}
+		fmt.Fprintf(&buf, "\t%s = %s.Register(%d, %s)\n", x.Name, registry, x.Code, strconv.Quote(x.Message))
+	}
+	buf.WriteString(")\n")
+
+	hasHTTPStatus := false
+	for _, x := range c.Codes {
+		hasHTTPStatus = hasHTTPStatus || x.HTTPStatus != 0
+	}
+	if hasHTTPStatus {
+		buf.WriteString("\nfunc init() {\n")
+		fmt.Fprintf(&buf, "\t%s.UpdateHTTPStatus(map[int32]int{\n", registry)
+		for _, x := range c.Codes {
+			if x.HTTPStatus != 0 {
+				fmt.Fprintf(&buf, "\t\t%d: %d,\n", x.Code, x.HTTPStatus)
+			}
+		}
+		buf.WriteString("\t})\n}\n")
+	}
+	return format.Source(buf.Bytes())
+}
+
+func (c *Catalog) generateMarkdown() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# Error Codes\n\n")
+	buf.WriteString("| Code | Name | HTTP Status | Message | Description |\n")
+	buf.WriteString("| ---- | ---- | ----------- | ------- | ----------- |\n")
+	for _, x := range c.Codes {
+		status := ""
+		if x.HTTPStatus != 0 {
+			status = fmt.Sprintf("%d %s", x.HTTPStatus, http.StatusText(x.HTTPStatus))
+		}
+		fmt.Fprintf(&buf, "| %d | %s | %s | %s | %s |\n",
+			x.Code, x.Name, status, escapeMarkdown(x.Message), escapeMarkdown(x.Description))
+	}
+	return buf.Bytes()
+}
+
+func escapeMarkdown(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\n", "<br>")
+	return s
+}
+
+func (c *Catalog) generateJSON() ([]byte, error) {
+	out, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	catalog, err := loadCatalog("testdata/errcodes.yaml")
+	require.Nil(t, err)
+	require.Nil(t, catalog.validate(true))
+
+	src, err := catalog.generateGo("errs", "errcodes.yaml")
+	require.Nil(t, err)
+	_, err = parser.ParseFile(token.NewFileSet(), "errcodes_gen.go", src, 0)
+	require.Nil(t, err)
+	got := string(src)
+	assert.Contains(t, got, "// Code generated by errcodegen from errcodes.yaml. DO NOT EDIT.")
+	assert.Contains(t, got, "var Registry = errcode.New()")
+	assert.Contains(t, got, "\t// The requested resource does not exist,\n\t// or it has been deleted.\n")
+	assert.Contains(t, got, `ErrNotFound = Registry.Register(100404, "resource not found")`)
+	assert.Contains(t, got, "Registry.UpdateHTTPStatus(map[int32]int{")
+	assert.Contains(t, got, "100404: 404,")
+
+	md := string(catalog.generateMarkdown())
+	assert.Contains(t, md, "| 100400 | ErrBadRequest | 400 Bad Request | bad request | The request parameters are invalid. |")
+	assert.Contains(t, md, "does not exist,<br>or it has been deleted. |")
+
+	out, err := catalog.generateJSON()
+	require.Nil(t, err)
+	var decoded map[string][]map[string]any
+	require.Nil(t, json.Unmarshal(out, &decoded))
+	assert.Len(t, decoded["codes"], 2)
+	assert.Equal(t, float64(404), decoded["codes"][1]["httpStatus"])
+}
+
+func TestGenerateTOML(t *testing.T) {
+	catalog, err := loadCatalog("testdata/errcodes.toml")
+	require.Nil(t, err)
+	require.Nil(t, catalog.validate(true))
+
+	src, err := catalog.generateGo("errs", "errcodes.toml")
+	require.Nil(t, err)
+	got := string(src)
+	assert.NotContains(t, got, "import")
+	assert.Contains(t, got, `ErrInternal = reg.Register(100500, "internal | error")`)
+	assert.NotContains(t, got, "UpdateHTTPStatus")
+	assert.Contains(t, string(catalog.generateMarkdown()), `internal \| error`)
+}
+
+func TestValidate(t *testing.T) {
+	catalog, err := parseCatalog([]byte(`
+codes:
+  - {code: 1, name: ErrA, message: a, description: a}
+  - {code: 1, name: ErrB, message: b, description: b}
+  - {code: 2, name: ErrA, message: c}
+  - {code: 3, name: errD, http_status: 999}
+`), "yaml")
+	require.Nil(t, err)
+	err = catalog.validate(true)
+	require.NotNil(t, err)
+	for _, want := range []string{
+		"code 1: duplicate with ErrA",
+		"code 2: name ErrA is already used by code 1",
+		"code 2: missing description",
+		`code 3: invalid name "errD"`,
+		"code 3: missing message",
+		"code 3: invalid http status 999",
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+	assert.NotContains(t, catalog.validate(false).Error(), "missing description")
+
+	_, err = parseCatalog([]byte("{}"), "json")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,93 @@
+// Command errcodegen generates Go source which registers error codes
+// to an errcode.Registry from a YAML or TOML catalog,
+// it also generates error code reference in markdown or JSON format
+// for API documents.
+//
+// It is designed to be used with go:generate, e.g.
+//
+//	//go:generate go run github.com/jxskiss/gopkg/v2/infra/errcode/cmd/errcodegen -in errcodes.yaml -out errcodes_gen.go -md ERRCODES.md
+//
+// A catalog looks like this:
+//
+//	# Optional, name of an existing *errcode.Registry variable in the
+//	# package, if it is empty, a variable "Registry" is declared.
+//	registry: reg
+//	codes:
+//	  - code: 100400
+//	    name: ErrBadRequest
+//	    message: bad request
+//	    http_status: 400
+//	    description: The request parameters are invalid.
+//
+// The catalog is validated before generating, duplicate codes or names,
+// invalid names and missing messages or descriptions are reported as errors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("errcodegen: ")
+
+	var (
+		inFile   = flag.String("in", "", "catalog file, in YAML or TOML format")
+		outFile  = flag.String("out", "errcodes_gen.go", "output Go source file")
+		pkgName  = flag.String("pkg", "", "package name of the generated Go source, default is $GOPACKAGE or name of the output directory")
+		mdFile   = flag.String("md", "", "optional output markdown reference file")
+		jsonFile = flag.String("json", "", "optional output JSON reference file")
+		noDocs   = flag.Bool("allow-missing-docs", false, "don't report error for codes which have no description")
+	)
+	flag.Parse()
+	if *inFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	catalog, err := loadCatalog(*inFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = catalog.validate(!*noDocs); err != nil {
+		log.Fatal(err)
+	}
+
+	pkg := *pkgName
+	if pkg == "" {
+		pkg = os.Getenv("GOPACKAGE")
+	}
+	if pkg == "" {
+		absOut, err := filepath.Abs(*outFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pkg = filepath.Base(filepath.Dir(absOut))
+	}
+
+	src, err := catalog.generateGo(pkg, filepath.Base(*inFile))
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeFile(*outFile, src)
+	if *mdFile != "" {
+		writeFile(*mdFile, catalog.generateMarkdown())
+	}
+	if *jsonFile != "" {
+		out, err := catalog.generateJSON()
+		if err != nil {
+			log.Fatal(err)
+		}
+		writeFile(*jsonFile, out)
+	}
+}
+
+func writeFile(name string, data []byte) {
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		log.Fatal(fmt.Errorf("cannot write file: %w", err))
+	}
+}
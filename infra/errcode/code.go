@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/jxskiss/gopkg/v2/internal"
 	"github.com/jxskiss/gopkg/v2/internal/unsafeheader"
 )
 
@@ -34,6 +35,9 @@ type Code struct {
 	msg     string
 	details []any
 	reg     *Registry
+
+	cause error
+	stack []uintptr
 }
 
 func (e *Code) String() string { return e.Error() }
@@ -55,6 +59,14 @@ func (e *Code) formatWithDetails(w io.Writer) {
 			io.WriteString(w, fmt.Sprintf("%+v", x))
 		}
 	}
+	if e.cause != nil {
+		io.WriteString(w, "; Cause: ")
+		io.WriteString(w, fmt.Sprintf("%+v", e.cause))
+	}
+	if frames := e.StackTrace(); len(frames) > 0 {
+		io.WriteString(w, "\n")
+		w.Write(internal.FormatFrames(frames))
+	}
 }
 
 // Error returns the error message, it implements the error interface.
@@ -92,6 +104,8 @@ func (e *Code) clone() *Code {
 		msg:     e.msg,
 		details: e.details[:detailsLen:detailsLen],
 		reg:     e.reg,
+		cause:   e.cause,
+		stack:   e.stack,
 	}
 }
 
@@ -99,6 +113,7 @@ func (e *Code) clone() *Code {
 func (e *Code) WithMessage(msg string) (code *Code) {
 	code = e.clone()
 	code.msg = msg
+	code.captureStack(0)
 	return
 }
 
@@ -107,16 +122,18 @@ func (e *Code) WithMessage(msg string) (code *Code) {
 func (e *Code) AddDetails(details ...any) (code *Code) {
 	code = e.clone()
 	code.details = append(code.details, details...)
+	code.captureStack(0)
 	return
 }
 
-// RemoveDetails returns a copy of Code without the error details.
-// If the Code does not have error details, it returns the Code
+// RemoveDetails returns a copy of Code without the error details,
+// the cause and the stack trace.
+// If the Code does not have any of them, it returns the Code
 // directly instead of a copy.
 // When returning an error code to end-users, you may want to remove
 // the error details which generally should not be exposed to them.
 func (e *Code) RemoveDetails() (code *Code) {
-	if len(e.details) == 0 {
+	if len(e.details) == 0 && e.cause == nil && e.stack == nil {
 		return e
 	}
 	return &Code{code: e.code, msg: e.msg, reg: e.reg}
@@ -0,0 +1,280 @@
+package errcode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WithDefaultLanguage returns an option to set the default language
+// of a Registry, the default language is used to resolve localized
+// messages when none of the requested languages has a translation.
+func WithDefaultLanguage(lang string) Option {
+	return Option{
+		applyRegistry: func(r *Registry) {
+			r.defaultLang = normalizeLanguage(lang)
+		},
+	}
+}
+
+// AddTranslations adds translated messages of a language to the registry.
+//
+// A message can contain placeholders which are filled by Code details
+// when it is resolved by Code.Localize, "{0}", "{1}" refers to
+// details by index, and "{name}" refers to a value by key in details
+// of type map[string]any or map[string]string.
+//
+// This method copies the underlying catalogs, it's safe for
+// concurrent use.
+func (p *Registry) AddTranslations(lang string, messages map[int32]string) {
+	p.addCatalogs(map[string]map[int32]string{lang: messages})
+}
+
+// addCatalogs merges catalogs into the registry as a whole.
+func (p *Registry) addCatalogs(catalogs map[string]map[int32]string) {
+	p.catalogsMu.Lock()
+	defer p.catalogsMu.Unlock()
+	oldCatalogs, _ := p.catalogs.Load().(map[string]map[int32]string)
+	newCatalogs := make(map[string]map[int32]string, len(oldCatalogs)+len(catalogs))
+	for l, msgs := range oldCatalogs {
+		newCatalogs[l] = msgs
+	}
+	for lang, messages := range catalogs {
+		lang = normalizeLanguage(lang)
+		newMsgs := make(map[int32]string, len(newCatalogs[lang])+len(messages))
+		for code, msg := range newCatalogs[lang] {
+			newMsgs[code] = msg
+		}
+		for code, msg := range messages {
+			newMsgs[code] = msg
+		}
+		newCatalogs[lang] = newMsgs
+	}
+	p.catalogs.Store(newCatalogs)
+}
+
+// LoadTranslations loads message catalogs from data, format can be
+// either "json" or "yaml". The data is a mapping from languages to
+// catalogs, e.g.
+//
+//	en:
+//	  100400: "invalid parameter {field}"
+//	zh:
+//	  100400: "参数 {field} 不合法"
+//
+// Catalogs are applied only if all of them are valid.
+func (p *Registry) LoadTranslations(data []byte, format string) error {
+	var catalogs map[string]map[string]string
+	var err error
+	switch strings.ToLower(format) {
+	case "json":
+		err = json.Unmarshal(data, &catalogs)
+	case "yaml", "yml":
+		err = yaml.Unmarshal(data, &catalogs)
+	default:
+		return fmt.Errorf("unsupported catalog format: %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal catalogs: %w", err)
+	}
+	parsed := make(map[string]map[int32]string, len(catalogs))
+	for lang, msgs := range catalogs {
+		messages := make(map[int32]string, len(msgs))
+		for key, msg := range msgs {
+			code, err := strconv.ParseInt(key, 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid error code %q for language %s", key, lang)
+			}
+			messages[int32(code)] = msg
+		}
+		parsed[lang] = messages
+	}
+	p.addCatalogs(parsed)
+	return nil
+}
+
+// LoadTranslationsFile loads message catalogs from a JSON or YAML file,
+// the format is determined by the file extension.
+// See LoadTranslations for the file content.
+func (p *Registry) LoadTranslationsFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("cannot read catalog file: %w", err)
+	}
+	format := strings.TrimPrefix(filepath.Ext(filename), ".")
+	return p.LoadTranslations(data, format)
+}
+
+func (p *Registry) getTranslation(code int32, langs []string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	catalogs, _ := p.catalogs.Load().(map[string]map[int32]string)
+	if len(catalogs) == 0 {
+		return "", false
+	}
+	for _, lang := range langs {
+		lang = normalizeLanguage(lang)
+		if msg, ok := catalogs[lang][code]; ok {
+			return msg, true
+		}
+		if base, _, found := strings.Cut(lang, "-"); found {
+			if msg, ok := catalogs[base][code]; ok {
+				return msg, true
+			}
+		}
+	}
+	if p.defaultLang != "" {
+		if msg, ok := catalogs[p.defaultLang][code]; ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// Localize returns the error message translated to the first language
+// in langs which has a translation, with placeholders filled by
+// the details, see Registry.AddTranslations.
+// A language tag such as "zh-CN" matches translations of "zh"
+// if "zh-CN" is not available.
+//
+// If the message is set by WithMessage, or none of the languages
+// and the default language has a translation, it returns Message()
+// with placeholders filled.
+func (e *Code) Localize(langs ...string) string {
+	msg := e.msg
+	if msg == "" {
+		var ok bool
+		msg, ok = e.reg.getTranslation(e.code, langs)
+		if !ok {
+			msg = e.reg.getMessage(e.code)
+		}
+	}
+	return fillParams(msg, e.details)
+}
+
+// LocalizeContext is like Localize, but it uses languages
+// associated with ctx, see NewLanguageContext.
+func (e *Code) LocalizeContext(ctx context.Context) string {
+	return e.Localize(LanguagesFromContext(ctx)...)
+}
+
+type langCtxKey struct{}
+
+// NewLanguageContext returns a copy of ctx with the languages attached,
+// languages should be in preference order.
+// It's typically used with ParseAcceptLanguage in HTTP middlewares.
+func NewLanguageContext(ctx context.Context, langs ...string) context.Context {
+	return context.WithValue(ctx, langCtxKey{}, langs)
+}
+
+// LanguagesFromContext returns the languages associated with ctx,
+// it returns nil if no languages are associated.
+func LanguagesFromContext(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	langs, _ := ctx.Value(langCtxKey{}).([]string)
+	return langs
+}
+
+// ParseAcceptLanguage parses an Accept-Language header value and
+// returns the languages sorted by quality values in descending order.
+// The wildcard "*" and languages with quality value 0 are ignored.
+func ParseAcceptLanguage(header string) []string {
+	type langQ struct {
+		lang string
+		q    float64
+	}
+	var list []langQ
+	for _, part := range strings.Split(header, ",") {
+		lang, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang = strings.TrimSpace(lang)
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.TrimSpace(name) == "q" {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		list = append(list, langQ{lang, q})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].q > list[j].q
+	})
+	out := make([]string, 0, len(list))
+	for _, x := range list {
+		out = append(out, x.lang)
+	}
+	return out
+}
+
+func normalizeLanguage(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+}
+
+// fillParams replaces placeholders in msg by details.
+// Placeholders which cannot be resolved are kept untouched.
+func fillParams(msg string, details []any) string {
+	if len(details) == 0 || !strings.Contains(msg, "{") {
+		return msg
+	}
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(msg, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(msg[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		b.WriteString(msg[:start])
+		if value, ok := lookupParam(msg[start+1:end], details); ok {
+			fmt.Fprint(&b, value)
+		} else {
+			b.WriteString(msg[start : end+1])
+		}
+		msg = msg[end+1:]
+	}
+	b.WriteString(msg)
+	return b.String()
+}
+
+func lookupParam(name string, details []any) (any, bool) {
+	if idx, err := strconv.Atoi(name); err == nil {
+		if idx >= 0 && idx < len(details) {
+			return details[idx], true
+		}
+		return nil, false
+	}
+	for _, x := range details {
+		switch m := x.(type) {
+		case map[string]any:
+			if value, ok := m[name]; ok {
+				return value, true
+			}
+		case map[string]string:
+			if value, ok := m[name]; ok {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
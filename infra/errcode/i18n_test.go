@@ -0,0 +1,51 @@
+package errcode
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalize(t *testing.T) {
+	reg := New(WithDefaultLanguage("en"))
+	badReq := reg.Register(100400, "bad request")
+	notFound := reg.Register(100404, "not found")
+	internal := reg.Register(100500, "internal error {0}")
+
+	require.Nil(t, reg.LoadTranslationsFile("testdata/catalog.yaml"))
+	require.Nil(t, reg.LoadTranslationsFile("testdata/catalog.json"))
+	assert.NotNil(t, reg.LoadTranslations([]byte("en:\n  abc: x\n"), "yaml"))
+	assert.NotNil(t, reg.LoadTranslations([]byte("{}"), "toml"))
+
+	// A partially invalid file is not applied at all.
+	invalid := "en:\n  100404: changed\nfr:\n  100404: changed\nde:\n  abc: x\nja:\n  100404: changed\n"
+	assert.NotNil(t, reg.LoadTranslations([]byte(invalid), "yaml"))
+	for _, lang := range []string{"en", "fr", "ja"} {
+		assert.NotEqual(t, "changed", notFound.Localize(lang))
+	}
+
+	err := badReq.AddDetails(map[string]any{"field": "name"})
+	assert.Equal(t, "参数 name 不合法", err.Localize("zh"))
+	assert.Equal(t, "参数 name 不合法", err.Localize("zh-CN", "en"))
+	assert.Equal(t, "參數 name 不合法", err.Localize("zh_TW"))
+	assert.Equal(t, "invalid parameter name", err.Localize("fr"))
+	assert.Equal(t, "invalid parameter {field}", badReq.Localize("en"))
+	assert.Equal(t, "[100400] bad request", err.Error())
+
+	assert.Equal(t, "user 不存在", notFound.AddDetails("user").Localize("zh"))
+	assert.Equal(t, "internal error db", internal.AddDetails("db").Localize("zh"))
+	assert.Equal(t, "custom name", err.WithMessage("custom {field}").Localize("zh"))
+
+	ctx := NewLanguageContext(context.Background(), ParseAcceptLanguage("fr;q=0.9, zh-CN;q=0.95, en;q=0.8")...)
+	assert.Equal(t, []string{"zh-CN", "fr", "en"}, LanguagesFromContext(ctx))
+	assert.Equal(t, "参数 name 不合法", err.LocalizeContext(ctx))
+	assert.Equal(t, "invalid parameter name", err.LocalizeContext(context.Background()))
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{}, ParseAcceptLanguage(""))
+	assert.Equal(t, []string{"en-US", "en", "zh"},
+		ParseAcceptLanguage("en-US, en;q=0.9, zh;q=0.5, *;q=0.1, fr;q=0"))
+}
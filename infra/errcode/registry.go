@@ -3,6 +3,7 @@ package errcode
 import (
 	"fmt"
 	"sort"
+	"sync"
 	"sync/atomic"
 )
 
@@ -11,6 +12,14 @@ type Registry struct {
 	reserve  func(code int32) bool // check reserved code
 	codes    map[int32]struct{}    // registry of all error codes
 	messages atomic.Value          // map[int32]string, copy on write
+
+	httpStatus atomic.Value // map[int32]int, copy on write
+	rpcCodes   atomic.Value // map[int32]RPCCode, copy on write
+
+	withStack   bool
+	defaultLang string
+	catalogsMu  sync.Mutex   // serializes writers of catalogs
+	catalogs    atomic.Value // map[string]map[int32]string, copy on write
 }
 
 // New creates a new error code registry.
@@ -0,0 +1,197 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Field numbers of google.rpc.Status and google.protobuf.Any.
+const (
+	statusCodeField    = 1
+	statusMessageField = 2
+	statusDetailsField = 3
+
+	anyTypeURLField = 1
+	anyValueField   = 2
+)
+
+var statusDesc = sync.OnceValues(func() (protoreflect.MessageDescriptor, error) {
+	// This is the same with google/rpc/status.proto, the file is built
+	// dynamically to avoid depending on genproto, and it is not
+	// registered to the global registry to avoid conflict with it.
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("github.com/jxskiss/gopkg/infra/errcode/status.proto"),
+		Package:    proto.String("google.rpc"),
+		Dependency: []string{"google/protobuf/any.proto"},
+		Syntax:     proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Status"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:     proto.String("code"),
+						JsonName: proto.String("code"),
+						Number:   proto.Int32(statusCodeField),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+					},
+					{
+						Name:     proto.String("message"),
+						JsonName: proto.String("message"),
+						Number:   proto.Int32(statusMessageField),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+					{
+						Name:     proto.String("details"),
+						JsonName: proto.String("details"),
+						Number:   proto.Int32(statusDetailsField),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".google.protobuf.Any"),
+					},
+				},
+			},
+		},
+	}
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		return nil, err
+	}
+	return fd.Messages().Get(0), nil
+})
+
+// ToStatus converts the Code to a google.rpc.Status-shaped protobuf
+// message, the returned message is a *dynamicpb.Message, it can be
+// marshaled and then unmarshalled to google.golang.org/genproto/googleapis/rpc/status.Status.
+//
+// The Status.Code field holds the integer error code (not the
+// canonical code, see RPCCode), the Status.Message field holds the
+// error message.
+// Details which are protobuf messages are packed into Any directly,
+// other details are converted to google.protobuf.Value and then packed
+// into Any, it returns an error if a detail cannot be converted.
+func (e *Code) ToStatus() (proto.Message, error) {
+	md, err := statusDesc()
+	if err != nil {
+		return nil, fmt.Errorf("cannot build status descriptor: %w", err)
+	}
+	msg := dynamicpb.NewMessage(md)
+	if err = e.FillStatus(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// FillStatus is like ToStatus, but it fills the given message,
+// which must be shaped as google.rpc.Status,
+// e.g. google.golang.org/genproto/googleapis/rpc/status.Status.
+func (e *Code) FillStatus(dst proto.Message) error {
+	m := dst.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	codeFd, msgFd, detailsFd, err := getStatusFields(fields)
+	if err != nil {
+		return err
+	}
+	m.Set(codeFd, protoreflect.ValueOfInt32(e.Code()))
+	if msg := e.Message(); msg != "" {
+		m.Set(msgFd, protoreflect.ValueOfString(msg))
+	}
+	if len(e.details) == 0 {
+		return nil
+	}
+	list := m.Mutable(detailsFd).List()
+	for i, x := range e.details {
+		anyMsg, err := detailToAny(x)
+		if err != nil {
+			return fmt.Errorf("cannot convert details[%d]: %w", i, err)
+		}
+		elem := list.NewElement()
+		elemMsg := elem.Message()
+		elemFields := elemMsg.Descriptor().Fields()
+		elemMsg.Set(elemFields.ByNumber(anyTypeURLField), protoreflect.ValueOfString(anyMsg.TypeUrl))
+		elemMsg.Set(elemFields.ByNumber(anyValueField), protoreflect.ValueOfBytes(anyMsg.Value))
+		list.Append(elem)
+	}
+	return nil
+}
+
+// FromStatus converts a google.rpc.Status-shaped protobuf message
+// to a Code, e.g. a message returned by Code.ToStatus or
+// google.golang.org/genproto/googleapis/rpc/status.Status.
+//
+// Details of types registered in protoregistry.GlobalTypes are
+// unpacked to the concrete message types, google.protobuf.Value
+// details are converted to Go values, other details are kept as *anypb.Any.
+// If the message equals to the registered message, it is not set
+// to the returned Code, thus message catalog of the registry is used.
+func (p *Registry) FromStatus(src proto.Message) (*Code, error) {
+	m := src.ProtoReflect()
+	codeFd, msgFd, detailsFd, err := getStatusFields(m.Descriptor().Fields())
+	if err != nil {
+		return nil, err
+	}
+	code := &Code{
+		code: int32(m.Get(codeFd).Int()),
+		reg:  p,
+	}
+	if msg := m.Get(msgFd).String(); msg != p.getMessage(code.code) {
+		code.msg = msg
+	}
+	list := m.Get(detailsFd).List()
+	for i := 0; i < list.Len(); i++ {
+		elemMsg := list.Get(i).Message()
+		elemFields := elemMsg.Descriptor().Fields()
+		anyMsg := &anypb.Any{
+			TypeUrl: elemMsg.Get(elemFields.ByNumber(anyTypeURLField)).String(),
+			Value:   elemMsg.Get(elemFields.ByNumber(anyValueField)).Bytes(),
+		}
+		code.details = append(code.details, anyToDetail(anyMsg))
+	}
+	return code, nil
+}
+
+func getStatusFields(fields protoreflect.FieldDescriptors) (codeFd, msgFd, detailsFd protoreflect.FieldDescriptor, err error) {
+	codeFd = fields.ByNumber(statusCodeField)
+	msgFd = fields.ByNumber(statusMessageField)
+	detailsFd = fields.ByNumber(statusDetailsField)
+	if codeFd == nil || codeFd.Kind() != protoreflect.Int32Kind ||
+		msgFd == nil || msgFd.Kind() != protoreflect.StringKind ||
+		detailsFd == nil || !detailsFd.IsList() || detailsFd.Message() == nil ||
+		detailsFd.Message().FullName() != "google.protobuf.Any" {
+		return nil, nil, nil, errors.New("message is not shaped as google.rpc.Status")
+	}
+	return codeFd, msgFd, detailsFd, nil
+}
+
+func detailToAny(x any) (*anypb.Any, error) {
+	if msg, ok := x.(proto.Message); ok {
+		return anypb.New(msg)
+	}
+	value, err := structpb.NewValue(x)
+	if err != nil {
+		return nil, err
+	}
+	return anypb.New(value)
+}
+
+func anyToDetail(anyMsg *anypb.Any) any {
+	msg, err := anyMsg.UnmarshalNew()
+	if err != nil {
+		return anyMsg
+	}
+	if value, ok := msg.(*structpb.Value); ok {
+		return value.AsInterface()
+	}
+	return msg
+}
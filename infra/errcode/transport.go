@@ -0,0 +1,211 @@
+package errcode
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// RPCCode is a canonical error code, the values are same with
+// google.golang.org/grpc/codes.Code, thus it can be converted to
+// codes.Code directly, e.g. codes.Code(rpcCode).
+type RPCCode uint32
+
+// Canonical error codes, see google.golang.org/grpc/codes for details.
+const (
+	OK                 RPCCode = 0
+	Canceled           RPCCode = 1
+	Unknown            RPCCode = 2
+	InvalidArgument    RPCCode = 3
+	DeadlineExceeded   RPCCode = 4
+	NotFound           RPCCode = 5
+	AlreadyExists      RPCCode = 6
+	PermissionDenied   RPCCode = 7
+	ResourceExhausted  RPCCode = 8
+	FailedPrecondition RPCCode = 9
+	Aborted            RPCCode = 10
+	OutOfRange         RPCCode = 11
+	Unimplemented      RPCCode = 12
+	Internal           RPCCode = 13
+	Unavailable        RPCCode = 14
+	DataLoss           RPCCode = 15
+	Unauthenticated    RPCCode = 16
+)
+
+var rpcCodeNames = [...]string{
+	OK:                 "OK",
+	Canceled:           "Canceled",
+	Unknown:            "Unknown",
+	InvalidArgument:    "InvalidArgument",
+	DeadlineExceeded:   "DeadlineExceeded",
+	NotFound:           "NotFound",
+	AlreadyExists:      "AlreadyExists",
+	PermissionDenied:   "PermissionDenied",
+	ResourceExhausted:  "ResourceExhausted",
+	FailedPrecondition: "FailedPrecondition",
+	Aborted:            "Aborted",
+	OutOfRange:         "OutOfRange",
+	Unimplemented:      "Unimplemented",
+	Internal:           "Internal",
+	Unavailable:        "Unavailable",
+	DataLoss:           "DataLoss",
+	Unauthenticated:    "Unauthenticated",
+}
+
+func (c RPCCode) String() string {
+	if int(c) < len(rpcCodeNames) {
+		return rpcCodeNames[c]
+	}
+	return "Code(" + strconv.FormatUint(uint64(c), 10) + ")"
+}
+
+// HTTPStatus returns the HTTP status code corresponding to the
+// canonical code, the mapping is same with grpc-gateway.
+func (c RPCCode) HTTPStatus() int {
+	switch c {
+	case OK:
+		return http.StatusOK
+	case Canceled:
+		return 499 // Client Closed Request
+	case Unknown:
+		return http.StatusInternalServerError
+	case InvalidArgument:
+		return http.StatusBadRequest
+	case DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case NotFound:
+		return http.StatusNotFound
+	case AlreadyExists:
+		return http.StatusConflict
+	case PermissionDenied:
+		return http.StatusForbidden
+	case Unauthenticated:
+		return http.StatusUnauthorized
+	case ResourceExhausted:
+		return http.StatusTooManyRequests
+	case FailedPrecondition:
+		// This deliberately doesn't translate to the similarly named '412 Precondition Failed'
+		// HTTP response status.
+		return http.StatusBadRequest
+	case Aborted:
+		return http.StatusConflict
+	case OutOfRange:
+		return http.StatusBadRequest
+	case Unimplemented:
+		return http.StatusNotImplemented
+	case Internal:
+		return http.StatusInternalServerError
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	case DataLoss:
+		return http.StatusInternalServerError
+	}
+	return http.StatusInternalServerError
+}
+
+// UpdateHTTPStatus updates the mapping from error codes to HTTP status
+// codes of the registry.
+// This method copies the underlying mapping, it's safe for
+// concurrent use.
+func (p *Registry) UpdateHTTPStatus(mapping map[int32]int) {
+	oldMapping, _ := p.httpStatus.Load().(map[int32]int)
+	newMapping := make(map[int32]int, len(oldMapping))
+	for code, status := range oldMapping {
+		newMapping[code] = status
+	}
+	for code, status := range mapping {
+		newMapping[code] = status
+	}
+	p.httpStatus.Store(newMapping)
+}
+
+// UpdateRPCCodes updates the mapping from error codes to canonical
+// codes of the registry.
+// This method copies the underlying mapping, it's safe for
+// concurrent use.
+func (p *Registry) UpdateRPCCodes(mapping map[int32]RPCCode) {
+	oldMapping, _ := p.rpcCodes.Load().(map[int32]RPCCode)
+	newMapping := make(map[int32]RPCCode, len(oldMapping))
+	for code, rpcCode := range oldMapping {
+		newMapping[code] = rpcCode
+	}
+	for code, rpcCode := range mapping {
+		newMapping[code] = rpcCode
+	}
+	p.rpcCodes.Store(newMapping)
+}
+
+func (p *Registry) getHTTPStatus(code int32) (int, bool) {
+	if p == nil {
+		return 0, false
+	}
+	mapping, _ := p.httpStatus.Load().(map[int32]int)
+	status, ok := mapping[code]
+	return status, ok
+}
+
+func (p *Registry) getRPCCode(code int32) (RPCCode, bool) {
+	if p == nil {
+		return 0, false
+	}
+	mapping, _ := p.rpcCodes.Load().(map[int32]RPCCode)
+	rpcCode, ok := mapping[code]
+	return rpcCode, ok
+}
+
+// HTTPStatus returns the HTTP status code mapped to the error code.
+//
+// If the error code is not mapped by Registry.UpdateHTTPStatus,
+// but mapped to a canonical code by Registry.UpdateRPCCodes,
+// it returns the HTTP status code corresponding to the canonical code.
+// Else it returns 500 (Internal Server Error).
+func (e *Code) HTTPStatus() int {
+	if status, ok := e.reg.getHTTPStatus(e.code); ok {
+		return status
+	}
+	if rpcCode, ok := e.reg.getRPCCode(e.code); ok {
+		return rpcCode.HTTPStatus()
+	}
+	return http.StatusInternalServerError
+}
+
+// RPCCode returns the canonical code mapped to the error code.
+// If the error code is not mapped by Registry.UpdateRPCCodes,
+// it returns Unknown.
+func (e *Code) RPCCode() RPCCode {
+	if rpcCode, ok := e.reg.getRPCCode(e.code); ok {
+		return rpcCode
+	}
+	return Unknown
+}
+
+// ToHTTPStatus returns the HTTP status code for err.
+// It returns 200 if err is nil, and 500 if err is not an ErrCode.
+// An ErrCode other than Code can provide an HTTP status code by
+// implementing the method "HTTPStatus() int".
+func ToHTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	if errCode := unwrapErrCode(err); errCode != nil {
+		if x, ok := errCode.(interface{ HTTPStatus() int }); ok {
+			return x.HTTPStatus()
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// ToRPCCode returns the canonical code for err.
+// It returns OK if err is nil, and Unknown if err is not an ErrCode.
+// An ErrCode other than Code can provide a canonical code by
+// implementing the method "RPCCode() RPCCode".
+func ToRPCCode(err error) RPCCode {
+	if err == nil {
+		return OK
+	}
+	if errCode := unwrapErrCode(err); errCode != nil {
+		if x, ok := errCode.(interface{ RPCCode() RPCCode }); ok {
+			return x.RPCCode()
+		}
+	}
+	return Unknown
+}
@@ -0,0 +1,83 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestTransportMapping(t *testing.T) {
+	reg := New()
+	badReq := reg.Register(100400, "bad request")
+	notFound := reg.Register(100404, "not found")
+	unknown := reg.Register(100500, "unknown")
+
+	reg.UpdateHTTPStatus(map[int32]int{100400: http.StatusBadRequest})
+	reg.UpdateRPCCodes(map[int32]RPCCode{
+		100400: InvalidArgument,
+		100404: NotFound,
+	})
+
+	assert.Equal(t, http.StatusBadRequest, badReq.HTTPStatus())
+	assert.Equal(t, InvalidArgument, badReq.RPCCode())
+	assert.Equal(t, http.StatusNotFound, notFound.HTTPStatus())
+	assert.Equal(t, NotFound, notFound.AddDetails("x").RPCCode())
+	assert.Equal(t, http.StatusInternalServerError, unknown.HTTPStatus())
+	assert.Equal(t, Unknown, unknown.RPCCode())
+
+	wrapped := fmt.Errorf("wrapped: %w", notFound)
+	assert.Equal(t, http.StatusNotFound, ToHTTPStatus(wrapped))
+	assert.Equal(t, NotFound, ToRPCCode(wrapped))
+	assert.Equal(t, http.StatusOK, ToHTTPStatus(nil))
+	assert.Equal(t, OK, ToRPCCode(nil))
+	assert.Equal(t, http.StatusInternalServerError, ToHTTPStatus(errors.New("other")))
+	assert.Equal(t, Unknown, ToRPCCode(errors.New("other")))
+
+	assert.Equal(t, "Unauthenticated", Unauthenticated.String())
+	assert.Equal(t, "Code(20)", RPCCode(20).String())
+	assert.Equal(t, 499, Canceled.HTTPStatus())
+}
+
+func TestStatus(t *testing.T) {
+	reg := New()
+	badReq := reg.Register(100400, "bad request")
+
+	err := badReq.AddDetails("invalid name", 123, map[string]any{"field": "name"}, durationpb.New(3e9))
+	status, convErr := err.ToStatus()
+	require.Nil(t, convErr)
+
+	buf, convErr := proto.Marshal(status)
+	require.Nil(t, convErr)
+	status2 := status.ProtoReflect().New().Interface()
+	require.Nil(t, proto.Unmarshal(buf, status2))
+
+	got, convErr := reg.FromStatus(status2)
+	require.Nil(t, convErr)
+	assert.True(t, errors.Is(got, badReq))
+	assert.Equal(t, "[100400] bad request", got.Error())
+	assert.Equal(t, "", got.msg)
+	require.Len(t, got.Details(), 4)
+	assert.Equal(t, "invalid name", got.Details()[0])
+	assert.Equal(t, float64(123), got.Details()[1])
+	assert.Equal(t, map[string]any{"field": "name"}, got.Details()[2])
+	assert.True(t, proto.Equal(durationpb.New(3e9), got.Details()[3].(proto.Message)))
+
+	status3, convErr := badReq.WithMessage("custom message").ToStatus()
+	require.Nil(t, convErr)
+	custom, convErr := reg.FromStatus(status3)
+	require.Nil(t, convErr)
+	assert.Equal(t, "[100400] custom message", custom.Error())
+
+	_, convErr = badReq.AddDetails(make(chan int)).ToStatus()
+	assert.NotNil(t, convErr)
+
+	_, convErr = reg.FromStatus(&anypb.Any{})
+	assert.NotNil(t, convErr)
+}
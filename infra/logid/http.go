@@ -0,0 +1,166 @@
+package logid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jxskiss/gopkg/v2/easy/ezhttp"
+	"github.com/jxskiss/gopkg/v2/zlog"
+)
+
+// DefaultHeader is the default HTTP header which carries log ID.
+const DefaultHeader = "X-Log-ID"
+
+// DefaultLogKey is the default key to add log ID to log attributes.
+const DefaultLogKey = "logid"
+
+// maxIncomingLength limits the length of a log ID read from an
+// incoming request, longer values are ignored and a new log ID
+// is generated.
+const maxIncomingLength = 128
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx with logID attached.
+// The parent context will be unaffected.
+func NewContext(ctx context.Context, logID string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, ctxKey{}, logID)
+}
+
+// FromContext returns the log ID attached to ctx,
+// it returns an empty string if no log ID is attached.
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	logID, _ := ctx.Value(ctxKey{}).(string)
+	return logID
+}
+
+// HTTPOptions customizes the behavior of Middleware and NewTransport.
+type HTTPOptions struct {
+
+	// Header specifies the HTTP header which carries log ID,
+	// default is DefaultHeader.
+	Header string
+
+	// Generator optionally specifies a generator to generate log IDs
+	// when an incoming request does not carry one,
+	// by default the default generator is used, see SetDefault.
+	Generator Generator
+
+	// LogKey specifies the key to add log ID to zlog prepended
+	// attributes, default is DefaultLogKey.
+	LogKey string
+
+	// DisableLogAttr disables adding log ID to zlog prepended attributes.
+	DisableLogAttr bool
+}
+
+func (p *HTTPOptions) getHeader() string {
+	if p != nil && p.Header != "" {
+		return p.Header
+	}
+	return DefaultHeader
+}
+
+func (p *HTTPOptions) gen() string {
+	if p != nil && p.Generator != nil {
+		return p.Generator.Gen()
+	}
+	return Gen()
+}
+
+func (p *HTTPOptions) getLogKey() string {
+	if p != nil && p.LogKey != "" {
+		return p.LogKey
+	}
+	return DefaultLogKey
+}
+
+// Middleware returns an http.Handler middleware which reads log ID
+// from an incoming request, or generates a new one if the request
+// does not carry one.
+//
+// The log ID is attached to the request context which can be retrieved
+// by FromContext, and set to the response header.
+// It is also added to the zlog prepended attributes, thus all logs
+// logged with the request context carry the log ID.
+func Middleware(opts *HTTPOptions) func(next http.Handler) http.Handler {
+	header := opts.getHeader()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logID := r.Header.Get(header)
+			if logID == "" || len(logID) > maxIncomingLength {
+				logID = opts.gen()
+			}
+			ctx := NewContext(r.Context(), logID)
+			if opts == nil || !opts.DisableLogAttr {
+				ctx = zlog.PrependAttrs(ctx, opts.getLogKey(), logID)
+			}
+			w.Header().Set(header, logID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// NewTransport returns an http.RoundTripper which propagates log ID
+// attached to the request context to outgoing requests.
+// If next is nil, http.DefaultTransport is used.
+//
+// A request which already carries the log ID header is not changed.
+func NewTransport(next http.RoundTripper, opts *HTTPOptions) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{
+		next:   next,
+		header: opts.getHeader(),
+	}
+}
+
+type transport struct {
+	next   http.RoundTripper
+	header string
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	logID := FromContext(req.Context())
+	if logID == "" || req.Header.Get(t.header) != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	// A RoundTripper should not modify the request.
+	req = req.Clone(req.Context())
+	req.Header.Set(t.header, logID)
+	return t.next.RoundTrip(req)
+}
+
+// SetRequestHeader sets the log ID attached to req.Context to
+// the request headers of an ezhttp.Request,
+// it does nothing if no log ID is attached or the header already exists.
+// It is useful when the request is not sent by a client using the
+// transport returned by NewTransport.
+func SetRequestHeader(req *ezhttp.Request, opts *HTTPOptions) *ezhttp.Request {
+	logID := FromContext(req.Context)
+	if req.Req != nil && logID == "" {
+		logID = FromContext(req.Req.Context())
+	}
+	if logID == "" {
+		return req
+	}
+	header := opts.getHeader()
+	for k := range req.Headers {
+		if http.CanonicalHeaderKey(k) == http.CanonicalHeaderKey(header) {
+			return req
+		}
+	}
+	if req.Headers == nil {
+		req.Headers = make(map[string]string)
+	}
+	req.Headers[header] = logID
+	return req
+}
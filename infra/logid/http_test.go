@@ -0,0 +1,87 @@
+package logid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jxskiss/gopkg/v2/easy/ezhttp"
+	"github.com/jxskiss/gopkg/v2/zlog"
+)
+
+func TestContext(t *testing.T) {
+	assert.Equal(t, "", FromContext(context.Background()))
+	assert.Equal(t, "", FromContext(nil))
+	ctx := NewContext(context.Background(), "abc")
+	assert.Equal(t, "abc", FromContext(ctx))
+}
+
+func TestMiddleware(t *testing.T) {
+	var gotLogID string
+	var gotAttr any
+	handler := Middleware(&HTTPOptions{Header: "X-Request-ID"})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotLogID = FromContext(r.Context())
+			attr := zlog.ExtractPrepended(r.Context(), time.Now(), "", 0)
+			gotAttr = attr.Value.Group()[0].Value.Any()
+		}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "incoming-id")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, "incoming-id", gotLogID)
+	assert.Equal(t, "incoming-id", gotAttr)
+	assert.Equal(t, "incoming-id", w.Header().Get("X-Request-ID"))
+
+	req = httptest.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.True(t, Decode(gotLogID).Valid())
+	assert.Equal(t, gotLogID, w.Header().Get("X-Request-ID"))
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", strings.Repeat("a", 200))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.True(t, Decode(gotLogID).Valid())
+}
+
+func TestTransport(t *testing.T) {
+	var gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get(DefaultHeader)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil, nil)}
+	ctx := NewContext(context.Background(), "outgoing-id")
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	resp, err := client.Do(req)
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "outgoing-id", gotHeader)
+	assert.Equal(t, "", req.Header.Get(DefaultHeader))
+
+	_, _, _, err = (&ezhttp.Request{URL: server.URL, Context: ctx, Client: client}).Do()
+	require.Nil(t, err)
+	assert.Equal(t, "outgoing-id", gotHeader)
+
+	ezReq := SetRequestHeader(&ezhttp.Request{URL: server.URL, Context: ctx}, nil)
+	_, _, _, err = ezReq.Do()
+	require.Nil(t, err)
+	assert.Equal(t, "outgoing-id", gotHeader)
+
+	ezReq = SetRequestHeader(&ezhttp.Request{
+		URL:     server.URL,
+		Context: ctx,
+		Headers: map[string]string{"x-log-id": "explicit"},
+	}, nil)
+	assert.Equal(t, map[string]string{"x-log-id": "explicit"}, ezReq.Headers)
+}
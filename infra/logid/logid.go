@@ -4,6 +4,7 @@ package logid
 
 import (
 	"encoding/base32"
+	"strings"
 	"time"
 
 	"github.com/jxskiss/gopkg/v2/perf/fastrand"
@@ -88,16 +89,32 @@ func formatTime(t time.Time) string {
 
 // minLength is the minimum length of a log ID generated by this package.
 // Update this constant when adding new generators.
-const minLength = v2IPv4Length
+const minLength = v3Length
 
 // Decode decodes a log ID string and returns the parsed information.
+//
+// A traceparent value generated by W3CGen is decoded as W3CInfo.
+// If s is a tracestate value, the log ID embedded by
+// TraceState.WithLogID is extracted and decoded.
 func Decode(s string) (info Info) {
+	if isTraceParent(s) {
+		return decodeW3CInfo(s)
+	}
+	if strings.IndexByte(s, '=') > 0 {
+		ts, err := ParseTraceState(s)
+		if err != nil {
+			return invalidInfo{}
+		}
+		return Decode(ts.LogID())
+	}
 	if len(s) >= minLength {
 		switch s[len(s)-1] {
 		case v1Version:
 			return decodeV1Info(s)
 		case v2Version:
 			return decodeV2Info(s)
+		case v3Version:
+			return decodeV3Info(s)
 		}
 	}
 	return invalidInfo{}
@@ -115,6 +132,7 @@ type Info interface {
 	// e.g.
 	// "1|20240125.10:07:20.485+0800|M0RY2MKE72XWXGSW|140NFEAD8J"
 	// "2|20240125.10:07:20.486+0800|fdbd:dc01:16:16::94|CBDDWZEJH4"
+	// "3|20240125.10:07:20.487+0800|361|0"
 	String() string
 }
 
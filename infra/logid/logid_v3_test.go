@@ -0,0 +1,120 @@
+package logid
+
+import (
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestV3Gen(t *testing.T) {
+	gen, err := NewV3Gen(&V3Options{IP: net.ParseIP("10.1.2.3")})
+	require.Nil(t, err)
+	now := time.Now().UnixMilli()
+
+	id := gen.Gen()
+	t.Log(id)
+	assert.Len(t, id, v3Length)
+
+	info := Decode(id)
+	require.True(t, info.Valid())
+	assert.Equal(t, byte(v3Version), info.Version())
+	v3 := info.(V3Info)
+	assert.True(t, math.Abs(float64(v3.Time().UnixMilli()-now)) <= 1)
+	assert.Equal(t, int64(2<<8|3), v3.WorkerID())
+	assert.Equal(t, id, gen.FormatInt(v3.Int64()))
+
+	intID := gen.GenInt()
+	assert.Greater(t, intID, v3.Int64())
+	intInfo := gen.DecodeInt(intID)
+	assert.Equal(t, v3.WorkerID(), intInfo.WorkerID())
+	assert.Equal(t, intID, Decode(gen.FormatInt(intID)).(V3Info).Int64())
+
+	assert.False(t, Decode("ZZZZZZZZZZZZZA3").Valid())
+	assert.False(t, Decode("0000000000000Z3").Valid())
+}
+
+func TestV3GenOptions(t *testing.T) {
+	workerID := int64(0x1234)
+	workerBits := 4
+	gen, err := NewV3Gen(&V3Options{WorkerBits: &workerBits, WorkerID: &workerID})
+	require.Nil(t, err)
+	info := Decode(gen.Gen()).(V3Info)
+	assert.Equal(t, int64(4), info.WorkerID())
+
+	workerBits = 0
+	gen, err = NewV3Gen(&V3Options{WorkerBits: &workerBits, WorkerID: &workerID})
+	require.Nil(t, err)
+	info = Decode(gen.Gen()).(V3Info)
+	assert.True(t, info.Valid())
+	assert.Equal(t, int64(0), info.WorkerID())
+
+	machineGen1, err := NewV3Gen(nil)
+	require.Nil(t, err)
+	machineGen2, err := NewV3Gen(nil)
+	require.Nil(t, err)
+	assert.Equal(t, machineGen1.worker, machineGen2.worker)
+
+	workerBits = 17
+	_, err = NewV3Gen(&V3Options{WorkerBits: &workerBits})
+	assert.NotNil(t, err)
+	_, err = NewV3Gen(&V3Options{IP: net.IP{10, 1, 2}})
+	assert.NotNil(t, err)
+}
+
+func TestV3GenMonotonic(t *testing.T) {
+	workerBits := 16
+	gen, err := NewV3Gen(&V3Options{WorkerBits: &workerBits})
+	require.Nil(t, err)
+
+	const N = 4
+	results := make([][]string, N)
+	var wg sync.WaitGroup
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				results[i] = append(results[i], gen.Gen())
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var all []string
+	for _, ids := range results {
+		// IDs generated by one goroutine are strictly increasing.
+		assert.True(t, sort.StringsAreSorted(ids))
+		all = append(all, ids...)
+	}
+	sort.Strings(all)
+	for i := 1; i < len(all); i++ {
+		require.NotEqual(t, all[i-1], all[i])
+		prev, curr := Decode(all[i-1]).(V3Info), Decode(all[i]).(V3Info)
+		require.Less(t, prev.Int64(), curr.Int64())
+	}
+}
+
+func BenchmarkV3Gen(b *testing.B) {
+	gen, _ := NewV3Gen(nil)
+	id3 := gen.Gen()
+
+	b.Run("generate", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = gen.Gen()
+		}
+	})
+
+	b.Run("decode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = Decode(id3)
+		}
+	})
+}
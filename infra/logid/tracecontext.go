@@ -0,0 +1,406 @@
+package logid
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jxskiss/gopkg/v2/perf/fastrand"
+)
+
+var _ Generator = &W3CGen{}
+var _ W3CInfo = &w3cInfo{}
+
+const (
+	w3cVersion = 'w'
+
+	// traceparentLength is the length of a version "00" traceparent
+	// header value, e.g.
+	// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+	traceparentLength = 55
+
+	// maxTraceStateMembers is the max number of list members
+	// in a tracestate header value.
+	maxTraceStateMembers = 32
+)
+
+// TraceStateKey is the key used to embed a log ID in tracestate,
+// see TraceState.WithLogID.
+const TraceStateKey = "logid"
+
+// FlagSampled is the sampled flag of trace flags in traceparent.
+const FlagSampled byte = 0x01
+
+// TraceID is a W3C Trace Context trace-id.
+type TraceID [16]byte
+
+// IsValid tells whether the trace ID is valid, that is not all zeros.
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// String returns the lowercase hex form of the trace ID.
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// SpanID is a W3C Trace Context span-id (parent-id).
+type SpanID [8]byte
+
+// IsValid tells whether the span ID is valid, that is not all zeros.
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// String returns the lowercase hex form of the span ID.
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// NewTraceID generates a new random trace ID.
+func NewTraceID() (id TraceID) {
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:8], fastrand.Uint64())
+		binary.BigEndian.PutUint64(id[8:], fastrand.Uint64())
+	}
+	return id
+}
+
+// NewSpanID generates a new random span ID.
+func NewSpanID() (id SpanID) {
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:], fastrand.Uint64())
+	}
+	return id
+}
+
+// TraceParent represents a W3C Trace Context traceparent header.
+// See https://www.w3.org/TR/trace-context/#traceparent-header.
+type TraceParent struct {
+	Version byte
+	TraceID TraceID
+	SpanID  SpanID
+	Flags   byte
+}
+
+// NewTraceParent creates a new version "00" TraceParent with random
+// trace ID and span ID.
+func NewTraceParent(sampled bool) TraceParent {
+	tp := TraceParent{
+		TraceID: NewTraceID(),
+		SpanID:  NewSpanID(),
+	}
+	if sampled {
+		tp.Flags = FlagSampled
+	}
+	return tp
+}
+
+// Sampled tells whether the sampled flag is set.
+func (tp TraceParent) Sampled() bool { return tp.Flags&FlagSampled != 0 }
+
+// IsValid tells whether both the trace ID and span ID are valid.
+func (tp TraceParent) IsValid() bool {
+	return tp.Version != 0xff && tp.TraceID.IsValid() && tp.SpanID.IsValid()
+}
+
+// NewChild returns a copy of tp with a new random span ID,
+// it is used to propagate the trace to downstream services.
+func (tp TraceParent) NewChild() TraceParent {
+	tp.SpanID = NewSpanID()
+	return tp
+}
+
+// String formats tp as a traceparent header value.
+func (tp TraceParent) String() string {
+	var buf [traceparentLength]byte
+	hex.Encode(buf[0:2], []byte{tp.Version})
+	buf[2] = '-'
+	hex.Encode(buf[3:35], tp.TraceID[:])
+	buf[35] = '-'
+	hex.Encode(buf[36:52], tp.SpanID[:])
+	buf[52] = '-'
+	hex.Encode(buf[53:55], []byte{tp.Flags})
+	return string(buf[:])
+}
+
+var errInvalidTraceParent = errors.New("invalid traceparent")
+
+// ParseTraceParent parses a traceparent header value.
+// Following the specification, values of future versions (greater
+// than "00") are parsed as version "00" if they are well-formed.
+func ParseTraceParent(s string) (tp TraceParent, err error) {
+	s = strings.TrimSpace(s)
+	if len(s) < traceparentLength ||
+		s[2] != '-' || s[35] != '-' || s[52] != '-' ||
+		!isLowerHex(s[:2]) || !isLowerHex(s[3:35]) || !isLowerHex(s[36:52]) || !isLowerHex(s[53:55]) {
+		return tp, errInvalidTraceParent
+	}
+	var b [1]byte
+	hex.Decode(b[:], []byte(s[0:2]))
+	tp.Version = b[0]
+	if tp.Version == 0xff ||
+		(tp.Version == 0 && len(s) != traceparentLength) ||
+		(len(s) > traceparentLength && s[traceparentLength] != '-') {
+		return TraceParent{}, errInvalidTraceParent
+	}
+	hex.Decode(tp.TraceID[:], []byte(s[3:35]))
+	hex.Decode(tp.SpanID[:], []byte(s[36:52]))
+	hex.Decode(b[:], []byte(s[53:55]))
+	tp.Flags = b[0]
+	if !tp.TraceID.IsValid() || !tp.SpanID.IsValid() {
+		return TraceParent{}, errInvalidTraceParent
+	}
+	return tp, nil
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// TraceStateMember is a key-value pair in tracestate.
+type TraceStateMember struct {
+	Key   string
+	Value string
+}
+
+// TraceState represents a W3C Trace Context tracestate header,
+// the first member is the most recently updated one.
+// See https://www.w3.org/TR/trace-context/#tracestate-header.
+type TraceState []TraceStateMember
+
+// ParseTraceState parses a tracestate header value.
+// Empty list members are ignored, it returns an error if any member
+// is invalid, or there are duplicate keys.
+func ParseTraceState(s string) (TraceState, error) {
+	var ts TraceState
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, found := strings.Cut(part, "=")
+		if !found || !isValidTraceStateKey(key) || !isValidTraceStateValue(value) {
+			return nil, fmt.Errorf("invalid tracestate member: %q", part)
+		}
+		if ts.Get(key) != "" {
+			return nil, fmt.Errorf("duplicate tracestate key: %q", key)
+		}
+		ts = append(ts, TraceStateMember{Key: key, Value: value})
+	}
+	if len(ts) > maxTraceStateMembers {
+		return nil, errors.New("too many tracestate members")
+	}
+	return ts, nil
+}
+
+// Get returns the value of key, it returns an empty string if key
+// does not exist.
+func (ts TraceState) Get(key string) string {
+	for _, m := range ts {
+		if m.Key == key {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+// Set returns a new TraceState with key set to value, the member is
+// moved to the beginning as the specification requires.
+// If the TraceState is full, the last member is dropped.
+// It returns an error if key or value is invalid.
+func (ts TraceState) Set(key, value string) (TraceState, error) {
+	if !isValidTraceStateKey(key) || !isValidTraceStateValue(value) {
+		return ts, fmt.Errorf("invalid tracestate member: %q=%q", key, value)
+	}
+	out := make(TraceState, 0, len(ts)+1)
+	out = append(out, TraceStateMember{Key: key, Value: value})
+	for _, m := range ts {
+		if m.Key != key && len(out) < maxTraceStateMembers {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// Delete returns a new TraceState without key.
+func (ts TraceState) Delete(key string) TraceState {
+	out := make(TraceState, 0, len(ts))
+	for _, m := range ts {
+		if m.Key != key {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// WithLogID returns a new TraceState with logID embedded using
+// the key TraceStateKey.
+func (ts TraceState) WithLogID(logID string) (TraceState, error) {
+	return ts.Set(TraceStateKey, logID)
+}
+
+// LogID returns the log ID embedded by WithLogID.
+// The returned log ID can be decoded by Decode.
+func (ts TraceState) LogID() string {
+	return ts.Get(TraceStateKey)
+}
+
+// String formats ts as a tracestate header value.
+func (ts TraceState) String() string {
+	var b strings.Builder
+	for i, m := range ts {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(m.Key)
+		b.WriteByte('=')
+		b.WriteString(m.Value)
+	}
+	return b.String()
+}
+
+func isValidTraceStateKey(key string) bool {
+	if len(key) == 0 || len(key) > 256 {
+		return false
+	}
+	tenant, system, multiTenant := strings.Cut(key, "@")
+	if multiTenant {
+		if len(tenant) == 0 || len(tenant) > 241 || len(system) == 0 || len(system) > 14 {
+			return false
+		}
+		return isValidKeyChars(tenant, true) && isValidKeyChars(system, false)
+	}
+	return isValidKeyChars(key, false)
+}
+
+func isValidKeyChars(s string, allowDigitFirst bool) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		isLower, isDigit := 'a' <= c && c <= 'z', '0' <= c && c <= '9'
+		if i == 0 {
+			if !isLower && !(allowDigitFirst && isDigit) {
+				return false
+			}
+			continue
+		}
+		if !isLower && !isDigit && c != '_' && c != '-' && c != '*' && c != '/' {
+			return false
+		}
+	}
+	return true
+}
+
+func isValidTraceStateValue(value string) bool {
+	if len(value) == 0 || len(value) > 256 || value[len(value)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+			return false
+		}
+	}
+	return true
+}
+
+// Metadata keys to propagate trace context, they are lowercase as
+// gRPC metadata requires.
+const (
+	TraceParentMDKey = "traceparent"
+	TraceStateMDKey  = "tracestate"
+)
+
+// InjectMetadata sets tp and ts to md, which can be a gRPC metadata.MD
+// or any map[string][]string carrier with lowercase keys.
+// An empty ts is not set.
+func InjectMetadata(md map[string][]string, tp TraceParent, ts TraceState) {
+	md[TraceParentMDKey] = []string{tp.String()}
+	if len(ts) > 0 {
+		md[TraceStateMDKey] = []string{ts.String()}
+	} else {
+		delete(md, TraceStateMDKey)
+	}
+}
+
+// ExtractMetadata parses traceparent and tracestate from md, which can
+// be a gRPC metadata.MD or any map[string][]string carrier with
+// lowercase keys.
+// Multiple tracestate values are combined as the specification requires.
+// It returns an error if traceparent is missing or invalid, an invalid
+// tracestate is ignored.
+func ExtractMetadata(md map[string][]string) (TraceParent, TraceState, error) {
+	values := md[TraceParentMDKey]
+	if len(values) != 1 {
+		return TraceParent{}, nil, errInvalidTraceParent
+	}
+	tp, err := ParseTraceParent(values[0])
+	if err != nil {
+		return TraceParent{}, nil, err
+	}
+	ts, _ := ParseTraceState(strings.Join(md[TraceStateMDKey], ","))
+	return tp, ts, nil
+}
+
+// NewW3CGen creates a new W3C Trace Context generator,
+// sampled specifies whether the sampled flag is set for generated
+// traceparent values.
+func NewW3CGen(sampled bool) *W3CGen {
+	return &W3CGen{sampled: sampled}
+}
+
+// W3CGen generates W3C Trace Context traceparent values as log IDs,
+// with random trace ID and span ID, so that log IDs line up with traces
+// emitted by OpenTelemetry SDKs.
+//
+// e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+type W3CGen struct {
+	sampled bool
+}
+
+// Gen generates a new traceparent value.
+func (p *W3CGen) Gen() string {
+	return NewTraceParent(p.sampled).String()
+}
+
+func isTraceParent(s string) bool {
+	return len(s) >= traceparentLength && s[2] == '-' && s[35] == '-' && s[52] == '-'
+}
+
+func decodeW3CInfo(s string) (info *w3cInfo) {
+	info = &w3cInfo{}
+	tp, err := ParseTraceParent(s)
+	if err != nil {
+		return
+	}
+	*info = w3cInfo{valid: true, tp: tp}
+	return
+}
+
+// W3CInfo holds parsed information of a traceparent value.
+type W3CInfo interface {
+	Info
+	TraceParent() TraceParent
+	TraceID() TraceID
+	SpanID() SpanID
+	Sampled() bool
+}
+
+type w3cInfo struct {
+	valid bool
+	tp    TraceParent
+}
+
+func (info *w3cInfo) Valid() bool              { return info != nil && info.valid }
+func (info *w3cInfo) Version() byte            { return w3cVersion }
+func (info *w3cInfo) TraceParent() TraceParent { return info.tp }
+func (info *w3cInfo) TraceID() TraceID         { return info.tp.TraceID }
+func (info *w3cInfo) SpanID() SpanID           { return info.tp.SpanID }
+func (info *w3cInfo) Sampled() bool            { return info.tp.Sampled() }
+
+func (info *w3cInfo) String() string {
+	if !info.Valid() {
+		return "w|invalid"
+	}
+	return fmt.Sprintf("w|%s|%s|%02x", info.tp.TraceID, info.tp.SpanID, info.tp.Flags)
+}
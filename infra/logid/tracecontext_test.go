@@ -0,0 +1,130 @@
+package logid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceParent(t *testing.T) {
+	const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tp, err := ParseTraceParent(header)
+	require.Nil(t, err)
+	assert.Equal(t, byte(0), tp.Version)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tp.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", tp.SpanID.String())
+	assert.True(t, tp.Sampled())
+	assert.True(t, tp.IsValid())
+	assert.Equal(t, header, tp.String())
+
+	child := tp.NewChild()
+	assert.Equal(t, tp.TraceID, child.TraceID)
+	assert.NotEqual(t, tp.SpanID, child.SpanID)
+
+	// Future versions may append more fields.
+	tp, err = ParseTraceParent("cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-what-the-future-will-be")
+	require.Nil(t, err)
+	assert.Equal(t, byte(0xcc), tp.Version)
+	assert.False(t, tp.Sampled())
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		_, err = ParseTraceParent(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestTraceState(t *testing.T) {
+	ts, err := ParseTraceState("rojo=00f067aa0ba902b7, congo=t61rcWkgMzE,,tenant@vendor=x")
+	require.Nil(t, err)
+	assert.Len(t, ts, 3)
+	assert.Equal(t, "t61rcWkgMzE", ts.Get("congo"))
+
+	ts, err = ts.Set("congo", "abc")
+	require.Nil(t, err)
+	assert.Equal(t, "congo=abc,rojo=00f067aa0ba902b7,tenant@vendor=x", ts.String())
+	assert.Equal(t, "congo=abc,tenant@vendor=x", ts.Delete("rojo").String())
+
+	_, err = ts.Set("Invalid", "x")
+	assert.NotNil(t, err)
+	_, err = ParseTraceState("a=1,a=2")
+	assert.NotNil(t, err)
+	_, err = ParseTraceState("a=1=2")
+	assert.NotNil(t, err)
+
+	var many []string
+	for i := 0; i < 33; i++ {
+		many = append(many, "k"+strings.Repeat("a", i)+"=v")
+	}
+	_, err = ParseTraceState(strings.Join(many, ","))
+	assert.NotNil(t, err)
+}
+
+func TestW3CGen(t *testing.T) {
+	gen := NewW3CGen(true)
+	id := gen.Gen()
+	assert.Len(t, id, traceparentLength)
+
+	info := Decode(id)
+	require.True(t, info.Valid())
+	assert.Equal(t, byte(w3cVersion), info.Version())
+	w3c := info.(W3CInfo)
+	assert.True(t, w3c.Sampled())
+	assert.True(t, w3c.TraceID().IsValid())
+	assert.Equal(t, id, w3c.TraceParent().String())
+	assert.Equal(t, "w|"+id[3:35]+"|"+id[36:52]+"|01", info.String())
+	assert.NotEqual(t, id, gen.Gen())
+
+	assert.False(t, NewW3CGen(false).Gen() == id)
+	assert.False(t, Decode("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz").Valid())
+}
+
+func TestTraceStateLogID(t *testing.T) {
+	logID := NewV2Gen(nil).Gen()
+	ts, err := TraceState{{Key: "rojo", Value: "00f067aa0ba902b7"}}.WithLogID(logID)
+	require.Nil(t, err)
+	assert.Equal(t, logID, ts.LogID())
+
+	info := Decode(ts.String())
+	require.True(t, info.Valid())
+	assert.Equal(t, byte(v2Version), info.Version())
+	assert.Equal(t, Decode(logID).String(), info.String())
+
+	assert.False(t, Decode("rojo=00f067aa0ba902b7").Valid())
+	assert.False(t, Decode("a=1,a=2").Valid())
+}
+
+func TestMetadata(t *testing.T) {
+	tp := NewTraceParent(true)
+	ts, err := TraceState{}.WithLogID(NewV2Gen(nil).Gen())
+	require.Nil(t, err)
+
+	md := map[string][]string{TraceStateMDKey: {"stale=1"}}
+	InjectMetadata(md, tp, ts)
+	gotTP, gotTS, err := ExtractMetadata(md)
+	require.Nil(t, err)
+	assert.Equal(t, tp, gotTP)
+	assert.Equal(t, ts, gotTS)
+
+	InjectMetadata(md, tp, nil)
+	assert.NotContains(t, md, TraceStateMDKey)
+
+	md[TraceStateMDKey] = []string{"a=1", "b=2"}
+	_, gotTS, err = ExtractMetadata(md)
+	require.Nil(t, err)
+	assert.Equal(t, "a=1,b=2", gotTS.String())
+
+	_, _, err = ExtractMetadata(map[string][]string{})
+	assert.NotNil(t, err)
+	_, _, err = ExtractMetadata(map[string][]string{TraceParentMDKey: {"invalid"}})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,229 @@
+package gopool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed cron expression, see ParseCron.
+type CronSchedule struct {
+	expr   string
+	second uint64
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	loc    *time.Location
+
+	// domStar and dowStar tell whether the day-of-month and day-of-week
+	// fields are "*" or "?", when both are restricted, a day matches
+	// if either field matches.
+	domStar bool
+	dowStar bool
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronSeconds = cronField{0, 59, nil}
+	cronMinutes = cronField{0, 59, nil}
+	cronHours   = cronField{0, 23, nil}
+	cronDom     = cronField{1, 31, nil}
+	cronMonths  = cronField{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDow = cronField{0, 6, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a cron expression.
+//
+// It accepts the standard 5 fields form "minute hour day-of-month
+// month day-of-week", and an optional leading seconds field (6 fields).
+// Each field supports "*", "?", lists "a,b", ranges "a-b" and steps
+// "*/n" or "a-b/n", month and day-of-week fields also accept
+// three-letter English names, day-of-week 7 is Sunday as well as 0.
+//
+// Descriptors "@yearly", "@annually", "@monthly", "@weekly", "@daily",
+// "@midnight" and "@hourly" are also supported.
+//
+// By default, the schedule runs in the location of the time passed to
+// Next, a prefix "CRON_TZ=<location> " or "TZ=<location> " specifies
+// a time zone, e.g. "CRON_TZ=Asia/Shanghai 0 8 * * mon-fri".
+func ParseCron(expr string) (*CronSchedule, error) {
+	s := &CronSchedule{expr: expr}
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		tz, rest, _ := strings.Cut(spec, " ")
+		_, name, _ := strings.Cut(tz, "=")
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load cron time zone: %w", err)
+		}
+		s.loc = loc
+		spec = strings.TrimSpace(rest)
+	}
+	if strings.HasPrefix(spec, "@") {
+		x, ok := cronDescriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("unrecognized cron descriptor: %q", spec)
+		}
+		spec = x
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 or 6 fields, got %d", expr, len(fields))
+	}
+
+	var err error
+	parse := func(dst *uint64, field string, spec cronField) {
+		if err == nil {
+			*dst, err = parseCronField(field, spec)
+		}
+	}
+	parse(&s.second, fields[0], cronSeconds)
+	parse(&s.minute, fields[1], cronMinutes)
+	parse(&s.hour, fields[2], cronHours)
+	parse(&s.dom, fields[3], cronDom)
+	parse(&s.month, fields[4], cronMonths)
+	parse(&s.dow, fields[5], cronField{0, 7, cronDow.names})
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = fields[3] == "*" || fields[3] == "?"
+	s.dowStar = fields[5] == "*" || fields[5] == "?"
+	return s, nil
+}
+
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bitset uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+		}
+		var lo, hi int
+		switch {
+		case rng == "*" || rng == "?":
+			lo, hi = spec.min, spec.max
+		default:
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = parseCronValue(loStr, spec); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = parseCronValue(hiStr, spec); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = spec.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		}
+		for i := lo; i <= hi; i += step {
+			bitset |= 1 << uint(i)
+		}
+	}
+	return bitset, nil
+}
+
+func parseCronValue(s string, spec cronField) (int, error) {
+	if x, ok := spec.names[strings.ToLower(s)]; ok {
+		return x, nil
+	}
+	x, err := strconv.Atoi(s)
+	if err != nil || x < spec.min || x > spec.max {
+		return 0, fmt.Errorf("invalid value %q, must be in range [%d, %d]", s, spec.min, spec.max)
+	}
+	return x, nil
+}
+
+// String returns the cron expression.
+func (s *CronSchedule) String() string {
+	return s.expr
+}
+
+// Next returns the next activation time later than t.
+// It returns a zero time if no time matches within five years.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	origLoc := t.Location()
+	if s.loc != nil {
+		t = t.In(s.loc)
+	}
+	loc := t.Location()
+	t = t.Truncate(time.Second).Add(time.Second)
+	yearLimit := t.Year() + 5
+
+	for t.Year() <= yearLimit {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		if !has(s.second, t.Second()) {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t.In(origLoc)
+	}
+	return time.Time{}
+}
+
+func (s *CronSchedule) matchDay(t time.Time) bool {
+	domMatch := has(s.dom, t.Day())
+	dowMatch := has(s.dow, int(t.Weekday()))
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func has(bitset uint64, i int) bool {
+	return bitset&(1<<uint(i)) != 0
+}
@@ -0,0 +1,84 @@
+package gopool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	base := time.Date(2024, 1, 31, 10, 20, 30, 0, time.UTC) // Wednesday
+	testCases := []struct {
+		expr string
+		want []time.Time
+	}{
+		{"* * * * *", []time.Time{
+			time.Date(2024, 1, 31, 10, 21, 0, 0, time.UTC),
+			time.Date(2024, 1, 31, 10, 22, 0, 0, time.UTC),
+		}},
+		{"*/15 * * * * *", []time.Time{
+			time.Date(2024, 1, 31, 10, 20, 45, 0, time.UTC),
+			time.Date(2024, 1, 31, 10, 21, 0, 0, time.UTC),
+		}},
+		{"0 9-17/4 * * mon-fri", []time.Time{
+			time.Date(2024, 1, 31, 13, 0, 0, 0, time.UTC),
+			time.Date(2024, 1, 31, 17, 0, 0, 0, time.UTC),
+			time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC),
+		}},
+		{"30 8 29 feb *", []time.Time{
+			time.Date(2024, 2, 29, 8, 30, 0, 0, time.UTC),
+			time.Date(2028, 2, 29, 8, 30, 0, 0, time.UTC),
+		}},
+		{"0 0 1,15 * 7", []time.Time{ // day-of-month OR day-of-week
+			time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 2, 11, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC),
+		}},
+		{"@monthly", []time.Time{
+			time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{"CRON_TZ=Asia/Shanghai 0 8 * * *", []time.Time{
+			time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC),
+		}},
+	}
+	for _, tc := range testCases {
+		sched, err := ParseCron(tc.expr)
+		require.Nil(t, err, tc.expr)
+		assert.Equal(t, tc.expr, sched.String())
+		next := base
+		for _, want := range tc.want {
+			next = sched.Next(next)
+			assert.True(t, want.Equal(next), "%s: want %v, got %v", tc.expr, want, next)
+		}
+	}
+
+	sched, err := ParseCron("0 0 30 2 *")
+	require.Nil(t, err)
+	assert.True(t, sched.Next(base).IsZero())
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"@every 1m",
+		"CRON_TZ=Invalid/Zone * * * * *",
+	} {
+		_, err := ParseCron(expr)
+		assert.NotNil(t, err, expr)
+	}
+}
@@ -18,6 +18,7 @@ package gopool
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -42,10 +43,36 @@ type Option struct {
 	// PanicHandler specifies a handler when panic occurs.
 	// By default, a panic message with stack information is logged.
 	PanicHandler PanicHandler
+
+	// MaxWorkers enables the strict bounded mode if it is greater than
+	// zero, the pool never runs more than MaxWorkers tasks concurrently.
+	// The concurrency is bounded by a semaphore, a task may still be run
+	// in a new goroutine when the task channel is full, but only after
+	// it has acquired a slot of the semaphore.
+	//
+	// When the pool is saturated, Go and CtxGo block until a worker is
+	// available, Submit waits until a worker is available or ctx is done,
+	// TrySubmit fails immediately.
+	MaxWorkers int
+
+	// EnableStats enables collecting task statistics, which are
+	// reported by GoPool.Stats. It is disabled by default to avoid the
+	// overhead of taking timestamps for each task.
+	EnableStats bool
+
+	// StatsHook optionally specifies a hook which is called after each
+	// task finishes, it can be used to report task latency to metrics
+	// systems.
+	// Setting a StatsHook also enables collecting statistics.
+	StatsHook StatsHook
 }
 
 type PanicHandler func(ctx context.Context, r any)
 
+// ErrPoolSaturated is returned by TrySubmit when a pool in strict
+// bounded mode is saturated.
+var ErrPoolSaturated = errors.New("gopool: pool is saturated")
+
 // DefaultOption returns the default option.
 func DefaultOption() *Option {
 	return &Option{
@@ -64,6 +91,10 @@ var defaultPanicHandler = func(ctx context.Context, r any) {
 
 var defaultPool = New("gopool.defaultPool", nil)
 
+func init() {
+	_ = Register(defaultPool)
+}
+
 // Go runs the given func in background
 func Go(f func()) {
 	defaultPool.CtxGo(context.Background(), f)
@@ -0,0 +1,112 @@
+package gopool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Group is a collection of tasks running on a GoPool, it is like
+// golang.org/x/sync/errgroup.Group, but the tasks run on pool workers.
+//
+// A Group must be created by GoPool.NewGroup.
+type Group struct {
+	pool   *GoPool
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// NewGroup returns a new Group and an associated Context derived from ctx.
+//
+// The derived Context is canceled the first time a task submitted
+// to the group returns a non-nil error or panics,
+// or the first time Wait returns, whichever occurs first.
+func (p *GoPool) NewGroup(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	g := &Group{
+		pool:   p,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	return g, ctx
+}
+
+// Future represents the result of a task submitted to a Group.
+type Future struct {
+	done chan struct{}
+	err  error
+}
+
+// Done returns a channel that's closed when the task is done.
+func (f *Future) Done() <-chan struct{} { return f.done }
+
+// Wait waits for the task to be done and returns its error.
+func (f *Future) Wait() error {
+	<-f.done
+	return f.err
+}
+
+// Go submits a task to the group, see Submit for details.
+func (g *Group) Go(f func() error) {
+	g.Submit(f)
+}
+
+// Submit submits a task to the group and returns a Future to wait
+// for the result of the task.
+//
+// The first call to return a non-nil error cancels the group's
+// context, its error will be returned by Wait.
+// A panic in f is handled by the pool's PanicHandler, and it is
+// reported as an error.
+//
+// If the pool is in strict bounded mode, Submit blocks until a
+// worker is available or the group's context is done, if the context
+// is done first, the task is not executed and the Future reports the
+// context's error.
+func (g *Group) Submit(f func() error) *Future {
+	future := &Future{done: make(chan struct{})}
+	g.wg.Add(1)
+	task := func() {
+		defer g.wg.Done()
+		defer close(future.done)
+		defer func() {
+			if r := recover(); r != nil {
+				g.pool.handlePanic(g.ctx, r)
+				future.err = fmt.Errorf("gopool: task panic: %v", r)
+				g.setError(future.err)
+				// Re-raise the panic for the pool to count it in stats.
+				panic(handledPanic{r})
+			}
+		}()
+		if err := f(); err != nil {
+			future.err = err
+			g.setError(err)
+		}
+	}
+	if err := g.pool.Submit(g.ctx, task); err != nil {
+		future.err = err
+		g.setError(err)
+		close(future.done)
+		g.wg.Done()
+	}
+	return future
+}
+
+func (g *Group) setError(err error) {
+	g.errOnce.Do(func() {
+		g.err = err
+		g.cancel(err)
+	})
+}
+
+// Wait blocks until all tasks submitted to the group have returned,
+// then returns the first non-nil error (if any) from them.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(g.err)
+	return g.err
+}
@@ -0,0 +1,137 @@
+package gopool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoPool_Strict(t *testing.T) {
+	o := DefaultOption()
+	o.MaxWorkers = 3
+	o.TaskChanBuffer = 1
+	p := New("TestGoPool_Strict", o)
+
+	var running, maxRunning int32
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		p.Go(func() {
+			defer wg.Done()
+			n := atomic.AddInt32(&running, 1)
+			for {
+				old := atomic.LoadInt32(&maxRunning)
+				if n <= old || atomic.CompareAndSwapInt32(&maxRunning, old, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		})
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(3))
+
+	// Saturate the pool.
+	block := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		require.Nil(t, p.TrySubmit(context.Background(), func() { <-block }))
+	}
+	assert.Equal(t, ErrPoolSaturated, p.TrySubmit(context.Background(), func() {}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, p.Submit(ctx, func() {}))
+
+	close(block)
+	done := make(chan struct{})
+	require.Nil(t, p.Submit(context.Background(), func() { close(done) }))
+	<-done
+
+	// A panic task releases its slot.
+	for i := 0; i < 5; i++ {
+		p.Go(func() { panic("test panic") })
+	}
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, p.TrySubmit(context.Background(), func() {}))
+}
+
+func TestGroup(t *testing.T) {
+	p := New("TestGroup", nil)
+
+	g, _ := p.NewGroup(context.Background())
+	var count int32
+	futures := make([]*Future, 0, 10)
+	for i := 0; i < 10; i++ {
+		futures = append(futures, g.Submit(func() error {
+			atomic.AddInt32(&count, 1)
+			return nil
+		}))
+	}
+	assert.Nil(t, g.Wait())
+	assert.Equal(t, int32(10), count)
+	for _, f := range futures {
+		assert.Nil(t, f.Wait())
+	}
+
+	testErr := errors.New("test error")
+	g, ctx := p.NewGroup(context.Background())
+	f1 := g.Submit(func() error { return testErr })
+	g.Go(func() error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return errors.New("not canceled")
+		}
+	})
+	assert.Equal(t, testErr, g.Wait())
+	assert.Equal(t, testErr, f1.Wait())
+	assert.Equal(t, testErr, context.Cause(ctx))
+}
+
+func TestGroup_Panic(t *testing.T) {
+	var handled int32
+	o := DefaultOption()
+	o.PanicHandler = func(ctx context.Context, r any) {
+		atomic.AddInt32(&handled, 1)
+	}
+	o.EnableStats = true
+	p := New("TestGroup_Panic", o)
+
+	g, ctx := p.NewGroup(context.Background())
+	f := g.Submit(func() error { panic("oops") })
+	err := g.Wait()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "oops")
+	assert.Equal(t, err, f.Wait())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&handled))
+	assert.NotNil(t, ctx.Err())
+	require.Eventually(t, func() bool {
+		return p.Stats().Panicked == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&handled))
+}
+
+func TestGroup_Strict(t *testing.T) {
+	o := DefaultOption()
+	o.MaxWorkers = 1
+	p := New("TestGroup_Strict", o)
+
+	g, _ := p.NewGroup(context.Background())
+	testErr := errors.New("test error")
+	g.Go(func() error {
+		time.Sleep(10 * time.Millisecond)
+		return testErr
+	})
+	// Blocks until the first task fails, then the context is canceled.
+	f := g.Submit(func() error { return nil })
+	assert.Equal(t, testErr, g.Wait())
+	assert.Equal(t, context.Canceled, f.Wait())
+}
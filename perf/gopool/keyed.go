@@ -0,0 +1,113 @@
+package gopool
+
+import (
+	"context"
+	"sync"
+)
+
+// KeyedExecutor runs tasks on a GoPool, tasks submitted with the same
+// key run sequentially in submission order, while tasks of different
+// keys run concurrently.
+//
+// A key occupies at most one pool worker at a time, the worker runs
+// the queued tasks of the key one by one, and the key's queue is
+// removed once it is drained, thus idle keys take no resources.
+// After running keyedDrainBatch tasks of a key, the worker is released
+// and the remaining tasks are resubmitted to the pool, thus a busy key
+// does not starve other keys.
+type KeyedExecutor[K comparable] struct {
+	pool *GoPool
+
+	mu     sync.Mutex
+	queues map[K]*keyedQueue
+}
+
+// keyedDrainBatch is the max number of tasks of a key run by a worker
+// before it is released.
+const keyedDrainBatch = 16
+
+type keyedQueue struct {
+	tasks []task
+}
+
+// NewKeyedExecutor creates a new KeyedExecutor which runs tasks on pool.
+// If pool is nil, the default pool is used.
+func NewKeyedExecutor[K comparable](pool *GoPool) *KeyedExecutor[K] {
+	if pool == nil {
+		pool = defaultPool
+	}
+	return &KeyedExecutor[K]{
+		pool:   pool,
+		queues: make(map[K]*keyedQueue),
+	}
+}
+
+// Go runs f after previously submitted tasks of the same key finish.
+func (e *KeyedExecutor[K]) Go(key K, f func()) {
+	e.CtxGo(context.Background(), key, f)
+}
+
+// CtxGo runs f after previously submitted tasks of the same key finish,
+// and it passes ctx to the pool's panic handler when panic happens.
+// A panic in f does not stop the following tasks of the key.
+func (e *KeyedExecutor[K]) CtxGo(ctx context.Context, key K, f func()) {
+	t := e.pool.newTask(ctx, f)
+	e.mu.Lock()
+	if q := e.queues[key]; q != nil {
+		q.tasks = append(q.tasks, t)
+		e.mu.Unlock()
+		return
+	}
+	q := &keyedQueue{}
+	e.queues[key] = q
+	e.mu.Unlock()
+
+	e.schedule(key, q, t)
+}
+
+// schedule submits a runner to the pool which drains the queue of key,
+// starting with t. The runner itself is not counted in the statistics
+// of the pool, each task it runs is counted instead.
+func (e *KeyedExecutor[K]) schedule(key K, q *keyedQueue, t task) {
+	if e.pool.sem != nil {
+		e.pool.sem <- struct{}{}
+	}
+	e.pool.dispatch(task{ctx: t.ctx, f: func() { e.drain(key, q, t) }})
+}
+
+func (e *KeyedExecutor[K]) drain(key K, q *keyedQueue, t task) {
+	for i := 1; ; i++ {
+		e.pool.execute(t, false)
+
+		e.mu.Lock()
+		if len(q.tasks) == 0 {
+			delete(e.queues, key)
+			e.mu.Unlock()
+			return
+		}
+		t = q.tasks[0]
+		q.tasks[0] = task{}
+		q.tasks = q.tasks[1:]
+		e.mu.Unlock()
+
+		if i >= keyedDrainBatch {
+			// Release the worker to let other keys run, in strict
+			// bounded mode, the runner waits for a free slot in a new
+			// goroutine, since this worker holds a slot.
+			if e.pool.sem != nil {
+				go e.schedule(key, q, t)
+			} else {
+				e.schedule(key, q, t)
+			}
+			return
+		}
+	}
+}
+
+// ActiveKeys returns the number of keys which have running or
+// pending tasks.
+func (e *KeyedExecutor[K]) ActiveKeys() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.queues)
+}
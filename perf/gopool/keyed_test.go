@@ -0,0 +1,141 @@
+package gopool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyedExecutor(t *testing.T) {
+	e := NewKeyedExecutor[int](New("TestKeyedExecutor", nil))
+
+	const keys, n = 10, 200
+	var mu sync.Mutex
+	got := make(map[int][]int)
+	var running [keys]int32
+	var overlapped int32
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		for k := 0; k < keys; k++ {
+			k, i := k, i
+			wg.Add(1)
+			e.Go(k, func() {
+				defer wg.Done()
+				if atomic.AddInt32(&running[k], 1) > 1 {
+					atomic.StoreInt32(&overlapped, 1)
+				}
+				mu.Lock()
+				got[k] = append(got[k], i)
+				mu.Unlock()
+				atomic.AddInt32(&running[k], -1)
+			})
+		}
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&overlapped))
+	for k := 0; k < keys; k++ {
+		require.Len(t, got[k], n)
+		assert.IsIncreasing(t, got[k])
+	}
+	require.Eventually(t, func() bool {
+		return e.ActiveKeys() == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestKeyedExecutor_Concurrent(t *testing.T) {
+	e := NewKeyedExecutor[string](nil)
+
+	block := make(chan struct{})
+	done := make(chan struct{})
+	e.Go("a", func() { <-block })
+	e.Go("a", func() { close(done) })
+	ran := make(chan struct{})
+	e.Go("b", func() { close(ran) })
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("task of key b is blocked by key a")
+	}
+	select {
+	case <-done:
+		t.Fatal("tasks of key a run out of order")
+	default:
+	}
+	assert.Equal(t, 1, e.ActiveKeys())
+	close(block)
+	<-done
+}
+
+func TestKeyedExecutor_Panic(t *testing.T) {
+	var panics int32
+	o := DefaultOption()
+	o.PanicHandler = func(ctx context.Context, r any) {
+		atomic.AddInt32(&panics, 1)
+	}
+	e := NewKeyedExecutor[int](New("TestKeyedExecutor_Panic", o))
+
+	done := make(chan struct{})
+	e.Go(1, func() { panic("test panic") })
+	e.Go(1, func() { close(done) })
+	<-done
+	assert.Equal(t, int32(1), atomic.LoadInt32(&panics))
+}
+
+func TestKeyedExecutor_Stats(t *testing.T) {
+	var hookCalls int32
+	o := DefaultOption()
+	o.StatsHook = func(ctx context.Context, s TaskStats) {
+		atomic.AddInt32(&hookCalls, 1)
+	}
+	p := New("TestKeyedExecutor_Stats", o)
+	e := NewKeyedExecutor[int](p)
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		e.Go(i%2, func() { defer wg.Done() })
+	}
+	wg.Wait()
+
+	require.Eventually(t, func() bool {
+		return p.Stats().Completed == n
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int64(n), p.Stats().Submitted)
+	assert.Equal(t, int32(n), atomic.LoadInt32(&hookCalls))
+}
+
+func TestKeyedExecutor_Fairness(t *testing.T) {
+	o := DefaultOption()
+	o.MaxWorkers = 1
+	e := NewKeyedExecutor[string](New("TestKeyedExecutor_Fairness", o))
+
+	// Key "hot" keeps getting tasks, key "cold" must not be starved.
+	var stop atomic.Bool
+	var hot func()
+	hot = func() {
+		if !stop.Load() {
+			e.Go("hot", hot)
+		}
+	}
+	e.Go("hot", hot)
+
+	ran := make(chan struct{})
+	go e.Go("cold", func() { close(ran) }) // it blocks while the worker is busy
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("key cold is starved by key hot")
+	}
+	stop.Store(true)
+	require.Eventually(t, func() bool {
+		return e.ActiveKeys() == 0
+	}, time.Second, time.Millisecond)
+}
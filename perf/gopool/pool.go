@@ -31,18 +31,32 @@ type GoPool struct {
 
 	panicHandler PanicHandler
 
+	// sem limits the number of concurrent tasks in strict bounded mode,
+	// it is nil if the pool is not in strict bounded mode.
+	sem chan struct{}
+
+	// statsEnabled tells whether task statistics are collected,
+	// see Option.EnableStats.
+	statsEnabled bool
+	statsHook    StatsHook
+	stats        poolStats
+
 	tasks     chan task
 	unixMilli int64
 }
 
 type task struct {
-	ctx context.Context
-	f   func()
+	ctx      context.Context
+	f        func()
+	submitAt time.Time
 }
 
 var noopTask = task{f: func() {}}
 
 // New creates a new GoPool with the given name and options.
+//
+// The pool is not registered to the registry of named pools,
+// call Register to make it visible to Lookup, Pools and AllStats.
 func New(name string, option *Option) *GoPool {
 	if option == nil {
 		option = DefaultOption()
@@ -52,8 +66,13 @@ func New(name string, option *Option) *GoPool {
 		maxIdle:      int32(option.MaxIdleWorkers),
 		maxAge:       option.WorkerMaxAge,
 		panicHandler: option.PanicHandler,
+		statsEnabled: option.EnableStats || option.StatsHook != nil,
+		statsHook:    option.StatsHook,
 		tasks:        make(chan task, option.TaskChanBuffer),
 	}
+	if option.MaxWorkers > 0 {
+		p.sem = make(chan struct{}, option.MaxWorkers)
+	}
 	if p.panicHandler == nil {
 		p.panicHandler = defaultPanicHandler
 	}
@@ -66,12 +85,83 @@ func (p *GoPool) Go(f func()) {
 }
 
 // CtxGo runs the given func in background, and it passes ctx to panic handler when happens.
+//
+// In strict bounded mode, it blocks until a worker is available,
+// see Option.MaxWorkers.
 func (p *GoPool) CtxGo(ctx context.Context, f func()) {
+	if p.sem != nil {
+		p.sem <- struct{}{}
+	}
+	p.submit(ctx, f)
+}
+
+// Submit runs the given func in background like CtxGo.
+//
+// In strict bounded mode, it waits until a worker is available or
+// ctx is done, and returns ctx.Err() if ctx is done before the task
+// is submitted. Else it always returns nil.
+func (p *GoPool) Submit(ctx context.Context, f func()) error {
+	if p.sem != nil {
+		select {
+		case p.sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	p.submit(ctx, f)
+	return nil
+}
+
+// TrySubmit runs the given func in background like CtxGo.
+//
+// In strict bounded mode, it returns ErrPoolSaturated immediately
+// if no worker is available. Else it always returns nil.
+func (p *GoPool) TrySubmit(ctx context.Context, f func()) error {
+	if p.sem != nil {
+		select {
+		case p.sem <- struct{}{}:
+		default:
+			return ErrPoolSaturated
+		}
+	}
+	p.submit(ctx, f)
+	return nil
+}
+
+// submit sends the task to workers, in strict bounded mode,
+// the caller must have acquired a slot of p.sem.
+func (p *GoPool) submit(ctx context.Context, f func()) {
+	p.dispatch(p.newTask(ctx, f))
+}
+
+// newTask creates a task and counts it as submitted if statistics
+// are enabled.
+func (p *GoPool) newTask(ctx context.Context, f func()) task {
+	t := task{ctx: ctx, f: f}
+	if p.statsEnabled {
+		p.stats.submitted.Add(1)
+		t.submitAt = time.Now()
+	}
+	return t
+}
+
+// dispatch sends the task to workers, in strict bounded mode,
+// the caller must have acquired a slot of p.sem.
+// A task with zero submitAt is not counted in the statistics.
+func (p *GoPool) dispatch(t task) {
+	if p.sem != nil {
+		g := t.f
+		t.f = func() {
+			defer func() { <-p.sem }()
+			g()
+		}
+	}
 	select {
-	case p.tasks <- task{ctx: ctx, f: f}:
+	case p.tasks <- t:
 	default:
-		// task queue is full, fallback to use go directly
-		go p.runTask(ctx, f)
+		// task queue is full, fallback to use go directly,
+		// in strict bounded mode, the concurrency is still limited by p.sem
+		go p.execute(t, true)
 		return
 	}
 	// luckily ... it's true when there are many idle workers
@@ -98,17 +188,30 @@ func (p *GoPool) CurrentWorkers() int {
 	return int(atomic.LoadInt32(&p.workers))
 }
 
-func (p *GoPool) runTask(ctx context.Context, f func()) {
+func (p *GoPool) runTask(ctx context.Context, f func()) (panicked bool) {
 	defer func(ctx context.Context, p *GoPool) {
 		if r := recover(); r != nil {
-			if p.panicHandler != nil {
-				p.panicHandler(ctx, r)
-			} else {
-				defaultPanicHandler(ctx, r)
+			panicked = true
+			if _, ok := r.(handledPanic); !ok {
+				p.handlePanic(ctx, r)
 			}
 		}
 	}(ctx, p)
 	f()
+	return false
+}
+
+// handledPanic is re-raised by a task which has already passed the
+// panic to the pool's PanicHandler, runTask counts it in the stats
+// without calling the handler again.
+type handledPanic struct{ r any }
+
+func (p *GoPool) handlePanic(ctx context.Context, r any) {
+	if p.panicHandler != nil {
+		p.panicHandler(ctx, r)
+	} else {
+		defaultPanicHandler(ctx, r)
+	}
 }
 
 func (p *GoPool) runWorker() {
@@ -120,7 +223,7 @@ func (p *GoPool) runWorker() {
 		for {
 			select {
 			case t := <-p.tasks:
-				p.runTask(t.ctx, t.f)
+				p.execute(t, false)
 			default:
 				return
 			}
@@ -131,7 +234,7 @@ func (p *GoPool) runWorker() {
 	maxAge := p.maxAge.Milliseconds()
 	createdAt := time.Now().UnixMilli()
 	for t := range p.tasks {
-		p.runTask(t.ctx, t.f)
+		p.execute(t, false)
 		// start ticker if it's NOT running
 		now := atomic.LoadInt64(tptr)
 		if now == 0 {
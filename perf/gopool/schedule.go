@@ -0,0 +1,199 @@
+package gopool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ScheduledTask is a handle of a task scheduled by RunAfter,
+// RunAtFixedRate, RunWithFixedDelay or RunCron.
+//
+// Scheduled tasks run on pool workers, a panic in a task is handled
+// by the pool's PanicHandler, and a periodic task keeps running
+// after panics.
+type ScheduledTask struct {
+	pool *GoPool
+	ctx  context.Context
+	f    func()
+
+	// next returns the next run time, given the time the previous run
+	// was scheduled at and the time it finished, a zero time stops
+	// the task. It is nil for one-shot tasks.
+	next func(scheduled, finished time.Time) time.Time
+
+	mu        sync.Mutex
+	timer     *time.Timer
+	scheduled time.Time
+	stopped   bool
+	done      chan struct{}
+	stopCtx   func() bool
+}
+
+// RunAfter runs f on the pool after delay.
+// The task is canceled when ctx is done.
+func (p *GoPool) RunAfter(ctx context.Context, delay time.Duration, f func()) *ScheduledTask {
+	return p.schedule(ctx, time.Now().Add(delay), f, nil)
+}
+
+// RunAtFixedRate runs f on the pool periodically, the first run starts
+// after initialDelay, subsequent runs start at initialDelay + n*period.
+// The task is canceled when ctx is done.
+//
+// Runs of the task never overlap, if a run takes longer than period,
+// the missed runs are skipped and the next run starts at the next
+// scheduled time.
+//
+// It panics if period is not positive.
+func (p *GoPool) RunAtFixedRate(ctx context.Context, initialDelay, period time.Duration, f func()) *ScheduledTask {
+	if period <= 0 {
+		panic("gopool: non-positive period for RunAtFixedRate")
+	}
+	next := func(scheduled, finished time.Time) time.Time {
+		n := scheduled.Add(period)
+		if n.Before(finished) {
+			n = n.Add((finished.Sub(n)/period + 1) * period)
+		}
+		return n
+	}
+	return p.schedule(ctx, time.Now().Add(initialDelay), f, next)
+}
+
+// RunWithFixedDelay runs f on the pool periodically, the first run
+// starts after initialDelay, each subsequent run starts delay after
+// the previous run finishes.
+// The task is canceled when ctx is done.
+//
+// It panics if delay is not positive.
+func (p *GoPool) RunWithFixedDelay(ctx context.Context, initialDelay, delay time.Duration, f func()) *ScheduledTask {
+	if delay <= 0 {
+		panic("gopool: non-positive delay for RunWithFixedDelay")
+	}
+	next := func(_, finished time.Time) time.Time {
+		return finished.Add(delay)
+	}
+	return p.schedule(ctx, time.Now().Add(initialDelay), f, next)
+}
+
+// RunCron runs f on the pool at times matching the cron expression,
+// see ParseCron for the supported syntax.
+// The task is canceled when ctx is done.
+//
+// Runs of the task never overlap, if a run takes longer than the
+// interval, the missed runs are skipped.
+func (p *GoPool) RunCron(ctx context.Context, expr string, f func()) (*ScheduledTask, error) {
+	sched, err := ParseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+	next := func(_, finished time.Time) time.Time {
+		return sched.Next(finished)
+	}
+	return p.schedule(ctx, sched.Next(time.Now()), f, next), nil
+}
+
+func (p *GoPool) schedule(ctx context.Context, at time.Time, f func(), next func(scheduled, finished time.Time) time.Time) *ScheduledTask {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	t := &ScheduledTask{
+		pool: p,
+		ctx:  ctx,
+		f:    f,
+		next: next,
+		done: make(chan struct{}),
+	}
+	t.stopCtx = context.AfterFunc(ctx, t.Cancel)
+	t.scheduleAt(at)
+	return t
+}
+
+// Cancel cancels the task, a run which has already started is not
+// interrupted.
+// It is safe to call Cancel multiple times.
+func (t *ScheduledTask) Cancel() {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return
+	}
+	t.stopped = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.mu.Unlock()
+	t.stopCtx()
+	close(t.done)
+}
+
+// Done returns a channel that's closed when the task is canceled,
+// or a one-shot task has finished running.
+func (t *ScheduledTask) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *ScheduledTask) scheduleAt(at time.Time) {
+	if at.IsZero() {
+		t.Cancel()
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return
+	}
+	t.scheduled = at
+	if t.timer == nil {
+		t.timer = time.AfterFunc(time.Until(at), t.fire)
+	} else {
+		t.timer.Reset(time.Until(at))
+	}
+}
+
+func (t *ScheduledTask) fire() {
+	t.mu.Lock()
+	stopped, scheduled := t.stopped, t.scheduled
+	t.mu.Unlock()
+	if stopped {
+		return
+	}
+	err := t.pool.Submit(t.ctx, func() {
+		defer t.afterRun(scheduled)
+		t.f()
+	})
+	if err != nil {
+		t.Cancel()
+	}
+}
+
+func (t *ScheduledTask) afterRun(scheduled time.Time) {
+	if t.next == nil {
+		t.Cancel()
+		return
+	}
+	t.scheduleAt(t.next(scheduled, time.Now()))
+}
+
+// RunAfter runs f on the default pool after delay,
+// see GoPool.RunAfter for details.
+func RunAfter(ctx context.Context, delay time.Duration, f func()) *ScheduledTask {
+	return defaultPool.RunAfter(ctx, delay, f)
+}
+
+// RunAtFixedRate runs f on the default pool periodically,
+// see GoPool.RunAtFixedRate for details.
+func RunAtFixedRate(ctx context.Context, initialDelay, period time.Duration, f func()) *ScheduledTask {
+	return defaultPool.RunAtFixedRate(ctx, initialDelay, period, f)
+}
+
+// RunWithFixedDelay runs f on the default pool periodically,
+// see GoPool.RunWithFixedDelay for details.
+func RunWithFixedDelay(ctx context.Context, initialDelay, delay time.Duration, f func()) *ScheduledTask {
+	return defaultPool.RunWithFixedDelay(ctx, initialDelay, delay, f)
+}
+
+// RunCron runs f on the default pool at times matching the cron
+// expression, see GoPool.RunCron for details.
+func RunCron(ctx context.Context, expr string, f func()) (*ScheduledTask, error) {
+	return defaultPool.RunCron(ctx, expr, f)
+}
@@ -0,0 +1,96 @@
+package gopool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAfter(t *testing.T) {
+	p := New("TestRunAfter", nil)
+
+	var count int32
+	start := time.Now()
+	task := p.RunAfter(context.Background(), 20*time.Millisecond, func() {
+		atomic.AddInt32(&count, 1)
+	})
+	<-task.Done()
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+	task = p.RunAfter(context.Background(), 20*time.Millisecond, func() {
+		atomic.AddInt32(&count, 1)
+	})
+	task.Cancel()
+	task.Cancel()
+	<-task.Done()
+	time.Sleep(40 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+}
+
+func TestRunAtFixedRate(t *testing.T) {
+	var panics int32
+	o := DefaultOption()
+	o.PanicHandler = func(ctx context.Context, r any) {
+		atomic.AddInt32(&panics, 1)
+	}
+	p := New("TestRunAtFixedRate", o)
+
+	var count int32
+	ctx, cancel := context.WithCancel(context.Background())
+	task := p.RunAtFixedRate(ctx, 0, 10*time.Millisecond, func() {
+		if atomic.AddInt32(&count, 1) == 2 {
+			panic("test panic")
+		}
+	})
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&count) >= 5
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-task.Done()
+
+	n := atomic.LoadInt32(&count)
+	time.Sleep(30 * time.Millisecond)
+	assert.LessOrEqual(t, atomic.LoadInt32(&count), n+1)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&panics))
+}
+
+func TestRunWithFixedDelay(t *testing.T) {
+	p := New("TestRunWithFixedDelay", nil)
+
+	var count, running, overlapped int32
+	task := p.RunWithFixedDelay(context.Background(), 0, time.Millisecond, func() {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&count, 1)
+	})
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&count) >= 3
+	}, time.Second, time.Millisecond)
+	task.Cancel()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&overlapped))
+}
+
+func TestRunCron(t *testing.T) {
+	p := New("TestRunCron", nil)
+
+	_, err := p.RunCron(context.Background(), "invalid", func() {})
+	assert.NotNil(t, err)
+
+	var count int32
+	task, err := p.RunCron(context.Background(), "* * * * * *", func() {
+		atomic.AddInt32(&count, 1)
+	})
+	require.Nil(t, err)
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&count) >= 1
+	}, 3*time.Second, 10*time.Millisecond)
+	task.Cancel()
+}
@@ -0,0 +1,188 @@
+package gopool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the statistics of a GoPool.
+type Stats struct {
+	Name string
+
+	// Workers is the number of current workers.
+	Workers int
+
+	// QueueDepth is the number of tasks waiting in the task queue.
+	QueueDepth int
+
+	// Submitted is the number of tasks submitted to the pool.
+	Submitted int64
+
+	// Completed is the number of tasks finished, including panicked ones.
+	Completed int64
+
+	// Panicked is the number of tasks which panicked.
+	Panicked int64
+
+	// Fallback is the number of tasks which are run by `go` directly
+	// because the task queue is full.
+	Fallback int64
+
+	// QueueWait is the total time tasks spent waiting in the queue
+	// before running, divides it by Completed to get the average.
+	QueueWait time.Duration
+
+	// RunTime is the total time tasks spent running,
+	// divides it by Completed to get the average.
+	RunTime time.Duration
+}
+
+// TaskStats holds statistics of a single task, it is passed to
+// Option.StatsHook after a task finishes.
+type TaskStats struct {
+	Pool      string
+	QueueWait time.Duration
+	RunTime   time.Duration
+	Panicked  bool
+	Fallback  bool
+}
+
+// StatsHook is a function which is called after each task finishes.
+// The ctx is the one provided when submitting the task.
+//
+// It is called in the worker goroutine, thus it should return quickly.
+type StatsHook func(ctx context.Context, s TaskStats)
+
+type poolStats struct {
+	submitted atomic.Int64
+	completed atomic.Int64
+	panicked  atomic.Int64
+	fallback  atomic.Int64
+	queueWait atomic.Int64
+	runTime   atomic.Int64
+}
+
+// Name returns the name of the pool.
+func (p *GoPool) Name() string {
+	return p.name
+}
+
+// Stats returns a snapshot of the statistics of the pool.
+// Task counters and durations are zero unless Option.EnableStats is
+// true or Option.StatsHook is set.
+func (p *GoPool) Stats() Stats {
+	return Stats{
+		Name:       p.name,
+		Workers:    p.CurrentWorkers(),
+		QueueDepth: len(p.tasks),
+		Submitted:  p.stats.submitted.Load(),
+		Completed:  p.stats.completed.Load(),
+		Panicked:   p.stats.panicked.Load(),
+		Fallback:   p.stats.fallback.Load(),
+		QueueWait:  time.Duration(p.stats.queueWait.Load()),
+		RunTime:    time.Duration(p.stats.runTime.Load()),
+	}
+}
+
+// execute runs a submitted task and records its statistics
+// if statistics are enabled.
+// Tasks without submitAt, e.g. noop tasks sent by the ticker,
+// are not counted.
+func (p *GoPool) execute(t task, fallback bool) {
+	if !p.statsEnabled || t.submitAt.IsZero() {
+		p.runTask(t.ctx, t.f)
+		return
+	}
+	if fallback {
+		p.stats.fallback.Add(1)
+	}
+	start := time.Now()
+	panicked := p.runTask(t.ctx, t.f)
+	end := time.Now()
+
+	ts := TaskStats{
+		Pool:      p.name,
+		QueueWait: start.Sub(t.submitAt),
+		RunTime:   end.Sub(start),
+		Panicked:  panicked,
+		Fallback:  fallback,
+	}
+	p.stats.queueWait.Add(int64(ts.QueueWait))
+	p.stats.runTime.Add(int64(ts.RunTime))
+	if panicked {
+		p.stats.panicked.Add(1)
+	}
+	p.stats.completed.Add(1)
+	if p.statsHook != nil {
+		p.statsHook(t.ctx, ts)
+	}
+}
+
+var registry sync.Map // name -> *GoPool
+
+// ErrDuplicatePoolName is returned by Register if another pool with
+// the same name is already registered.
+var ErrDuplicatePoolName = errors.New("gopool: duplicate pool name")
+
+// Register adds p to the registry of named pools, which makes it
+// visible to Lookup, Pools and AllStats.
+//
+// Pools are not registered by New. A registered pool stays in the
+// registry until Unregister is called, thus pools which are created
+// dynamically, e.g. per tenant or per request, must be unregistered
+// when they are no longer used, else they are leaked.
+//
+// It returns an error if p has an empty name, or another pool with
+// the same name is already registered.
+func Register(p *GoPool) error {
+	if p.name == "" {
+		return errors.New("gopool: cannot register pool with empty name")
+	}
+	if actual, loaded := registry.LoadOrStore(p.name, p); loaded && actual != p {
+		return fmt.Errorf("%w: %s", ErrDuplicatePoolName, p.name)
+	}
+	return nil
+}
+
+// Unregister removes the pool with the given name from the registry
+// of named pools.
+func Unregister(name string) {
+	registry.Delete(name)
+}
+
+// Lookup returns the registered pool with the given name,
+// it returns nil if not found.
+func Lookup(name string) *GoPool {
+	if p, ok := registry.Load(name); ok {
+		return p.(*GoPool)
+	}
+	return nil
+}
+
+// Pools returns all registered pools, sorted by name.
+// See Register for the lifecycle of registered pools.
+func Pools() []*GoPool {
+	var out []*GoPool
+	registry.Range(func(_, value any) bool {
+		out = append(out, value.(*GoPool))
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
+
+// AllStats returns statistics of all registered pools, sorted by name.
+// It is useful to implement a debug endpoint to list pools.
+func AllStats() []Stats {
+	pools := Pools()
+	out := make([]Stats, 0, len(pools))
+	for _, p := range pools {
+		out = append(out, p.Stats())
+	}
+	return out
+}
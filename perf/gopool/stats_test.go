@@ -0,0 +1,100 @@
+package gopool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoPool_Stats(t *testing.T) {
+	var hookCalls, hookPanics int32
+	o := DefaultOption()
+	o.PanicHandler = func(ctx context.Context, r any) {}
+	o.StatsHook = func(ctx context.Context, s TaskStats) {
+		assert.Equal(t, "TestGoPool_Stats", s.Pool)
+		atomic.AddInt32(&hookCalls, 1)
+		if s.Panicked {
+			atomic.AddInt32(&hookPanics, 1)
+		}
+	}
+	p := New("TestGoPool_Stats", o)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		p.Go(func() {
+			defer wg.Done()
+			time.Sleep(time.Millisecond)
+		})
+	}
+	wg.Add(1)
+	p.Go(func() {
+		defer wg.Done()
+		panic("test panic")
+	})
+	wg.Wait()
+
+	require.Eventually(t, func() bool {
+		return p.Stats().Completed == 11
+	}, time.Second, time.Millisecond)
+
+	s := p.Stats()
+	assert.Equal(t, "TestGoPool_Stats", s.Name)
+	assert.Equal(t, int64(11), s.Submitted)
+	assert.Equal(t, int64(1), s.Panicked)
+	assert.Equal(t, int64(0), s.Fallback)
+	assert.GreaterOrEqual(t, s.RunTime, 10*time.Millisecond)
+	assert.Equal(t, int32(11), atomic.LoadInt32(&hookCalls))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hookPanics))
+}
+
+func TestGoPool_StatsFallback(t *testing.T) {
+	o := DefaultOption()
+	o.TaskChanBuffer = 0
+	o.EnableStats = true
+	p := New("TestGoPool_StatsFallback", o)
+
+	done := make(chan struct{})
+	p.Go(func() { <-done })
+	p.Go(func() {})
+	close(done)
+
+	require.Eventually(t, func() bool {
+		return p.Stats().Completed == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int64(2), p.Stats().Fallback)
+}
+
+func TestRegistry(t *testing.T) {
+	p1 := New("TestRegistry_b", nil)
+	p2 := New("TestRegistry_a", nil)
+	assert.Nil(t, Lookup("TestRegistry_b"))
+
+	require.Nil(t, Register(p1))
+	require.Nil(t, Register(p2))
+	require.Nil(t, Register(p1))
+	assert.NotNil(t, Register(New("", nil)))
+	err := Register(New("TestRegistry_a", nil))
+	assert.ErrorIs(t, err, ErrDuplicatePoolName)
+
+	assert.Same(t, p1, Lookup("TestRegistry_b"))
+	assert.Same(t, p2, Lookup("TestRegistry_a"))
+	assert.Same(t, defaultPool, Lookup("gopool.defaultPool"))
+	assert.Nil(t, Lookup(""))
+
+	var names []string
+	for _, s := range AllStats() {
+		names = append(names, s.Name)
+	}
+	assert.Contains(t, names, "TestRegistry_a")
+	assert.IsIncreasing(t, names)
+
+	Unregister("TestRegistry_a")
+	Unregister("TestRegistry_b")
+	assert.Nil(t, Lookup("TestRegistry_a"))
+}
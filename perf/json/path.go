@@ -0,0 +1,181 @@
+package json
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jxskiss/gopkg/v2/internal/unsafeheader"
+)
+
+// Result is a value extracted by Get.
+type Result struct {
+	// Kind is the kind of the value, it is InvalidToken if the value
+	// does not exist. It is ObjectStart or ArrayStart for an object
+	// or an array.
+	Kind TokenKind
+
+	// Raw is the raw JSON bytes of the value, it references the input
+	// data passed to Get.
+	Raw []byte
+}
+
+// Exists tells whether the value exists.
+func (r Result) Exists() bool {
+	return r.Kind != InvalidToken
+}
+
+// String returns the unquoted string for a string value, the raw JSON
+// for other values, or an empty string if the value does not exist
+// or is null.
+func (r Result) String() string {
+	switch r.Kind {
+	case InvalidToken, NullToken:
+		return ""
+	case StringToken:
+		s, _ := unquote(r.Raw)
+		return s
+	}
+	return string(r.Raw)
+}
+
+// Int returns the value as int64, it returns 0 if the value is not
+// a number or cannot be represented as int64.
+// A float number is truncated.
+func (r Result) Int() int64 {
+	if r.Kind != NumberToken {
+		return 0
+	}
+	s := unsafeheader.BytesToString(r.Raw)
+	if x, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return x
+	}
+	f, _ := strconv.ParseFloat(s, 64)
+	return int64(f)
+}
+
+// Float returns the value as float64, it returns 0 if the value is
+// not a number.
+func (r Result) Float() float64 {
+	if r.Kind != NumberToken {
+		return 0
+	}
+	f, _ := strconv.ParseFloat(unsafeheader.BytesToString(r.Raw), 64)
+	return f
+}
+
+// Bool returns true if the value is JSON true.
+func (r Result) Bool() bool {
+	return r.Kind == TrueToken
+}
+
+// Unmarshal decodes the value into v.
+func (r Result) Unmarshal(v any) error {
+	return Unmarshal(r.Raw, v)
+}
+
+// Get extracts the value specified by path from data, without
+// decoding the whole document.
+//
+// The path is a series of object keys or array indexes separated by
+// dots, e.g. "user.friends.0.name". A literal dot in a key can be
+// escaped by a backslash, e.g. "a\.b" matches key "a.b".
+// An empty path returns the first top-level value.
+//
+// Get stops parsing once the value is found, data after the value is
+// not validated. If the path does not exist or the JSON before the
+// value is invalid, the returned Result's Exists method reports false.
+func Get(data []byte, path string) Result {
+	tok := NewBytesTokenizer(data)
+	if !tok.Next() {
+		return Result{}
+	}
+	for _, seg := range splitPath(path) {
+		var found bool
+		switch tok.Token().Kind {
+		case ObjectStart:
+			found = seekKey(tok, seg)
+		case ArrayStart:
+			found = seekIndex(tok, seg)
+		}
+		if !found {
+			return Result{}
+		}
+	}
+	kind := tok.Token().Kind
+	raw, err := tok.RawValue()
+	if err != nil {
+		return Result{}
+	}
+	return Result{Kind: kind, Raw: raw}
+}
+
+// seekKey advances tok to the value of key in the current object.
+func seekKey(tok *Tokenizer, key string) bool {
+	for tok.Next() {
+		t := tok.Token()
+		if t.Kind != KeyToken {
+			return false
+		}
+		if keyEquals(t.Raw, key) {
+			return tok.Next()
+		}
+		if tok.Skip() != nil {
+			return false
+		}
+	}
+	return false
+}
+
+// seekIndex advances tok to the element at index idx in the current array.
+func seekIndex(tok *Tokenizer, idx string) bool {
+	n, err := strconv.Atoi(idx)
+	if err != nil || n < 0 {
+		return false
+	}
+	for i := 0; tok.Next(); i++ {
+		if tok.Token().Kind == ArrayEnd {
+			return false
+		}
+		if i == n {
+			return true
+		}
+		if tok.Skip() != nil {
+			return false
+		}
+	}
+	return false
+}
+
+func keyEquals(raw []byte, key string) bool {
+	s := raw[1 : len(raw)-1]
+	if !strings.Contains(unsafeheader.BytesToString(s), `\`) {
+		return unsafeheader.BytesToString(s) == key
+	}
+	unquoted, err := unquote(raw)
+	return err == nil && unquoted == key
+}
+
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	if !strings.Contains(path, `\`) {
+		return strings.Split(path, ".")
+	}
+	var segs []string
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '\\' && i+1 < len(path):
+			i++
+			b.WriteByte(path[i])
+		case c == '.':
+			segs = append(segs, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return append(segs, b.String())
+}
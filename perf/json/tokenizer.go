@@ -0,0 +1,613 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/jxskiss/gopkg/v2/internal/unsafeheader"
+)
+
+// TokenKind is the kind of a Token.
+type TokenKind uint8
+
+const (
+	InvalidToken TokenKind = iota
+	ObjectStart
+	ObjectEnd
+	ArrayStart
+	ArrayEnd
+	KeyToken
+	StringToken
+	NumberToken
+	TrueToken
+	FalseToken
+	NullToken
+)
+
+var tokenKindNames = [...]string{
+	InvalidToken: "invalid",
+	ObjectStart:  "object_start",
+	ObjectEnd:    "object_end",
+	ArrayStart:   "array_start",
+	ArrayEnd:     "array_end",
+	KeyToken:     "key",
+	StringToken:  "string",
+	NumberToken:  "number",
+	TrueToken:    "true",
+	FalseToken:   "false",
+	NullToken:    "null",
+}
+
+func (k TokenKind) String() string {
+	if int(k) < len(tokenKindNames) {
+		return tokenKindNames[k]
+	}
+	return "invalid"
+}
+
+// Token is a JSON token read by Tokenizer.
+type Token struct {
+	Kind TokenKind
+
+	// Raw is the raw bytes of the token, quotes are included for
+	// strings and keys.
+	// It is only valid until the next call to Tokenizer's methods.
+	Raw []byte
+
+	// Offset is the offset of the token in the input.
+	Offset int64
+
+	// Depth is the nesting depth of the token, top-level values are
+	// at depth 0, keys and values of a top-level object are at depth 1.
+	Depth int
+}
+
+// Unquote returns the unquoted string of a string or key token.
+func (t Token) Unquote() (string, error) {
+	if t.Kind != StringToken && t.Kind != KeyToken {
+		return "", fmt.Errorf("json: cannot unquote %s token", t.Kind)
+	}
+	return unquote(t.Raw)
+}
+
+// Int parses a number token as int64.
+func (t Token) Int() (int64, error) {
+	return strconv.ParseInt(unsafeheader.BytesToString(t.Raw), 10, 64)
+}
+
+// Float parses a number token as float64.
+func (t Token) Float() (float64, error) {
+	return strconv.ParseFloat(unsafeheader.BytesToString(t.Raw), 64)
+}
+
+func unquote(raw []byte) (string, error) {
+	if len(raw) < 2 {
+		return "", errors.New("json: invalid string")
+	}
+	s := raw[1 : len(raw)-1]
+	if bytes.IndexByte(s, '\\') < 0 {
+		return string(s), nil
+	}
+	var out string
+	err := json.Unmarshal(raw, &out)
+	return out, err
+}
+
+// SyntaxError is returned by Tokenizer when the input is not valid JSON.
+type SyntaxError struct {
+	Offset int64
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("json: %s at offset %d", e.Msg, e.Offset)
+}
+
+const tokenizerBufSize = 32 << 10
+
+// tokenizer states, tell what is expected next
+const (
+	stValue = iota
+	stValueOrEnd
+	stKey
+	stKeyOrEnd
+	stColon
+	stCommaOrEnd
+)
+
+// Tokenizer reads JSON tokens from an io.Reader or a byte slice
+// without building intermediate values.
+//
+// It accepts a stream of top-level values, e.g. newline-delimited JSON.
+// Top-level numbers and literals must be followed by whitespace,
+// objects, arrays and strings are self-delimiting and may be followed
+// by the next value directly.
+// When reading from an io.Reader, the memory used is bounded by the
+// size of the largest single token, thus it is suitable to process
+// very large input.
+//
+// A typical usage:
+//
+//	tok := NewTokenizer(r)
+//	for tok.Next() {
+//		t := tok.Token()
+//		...
+//	}
+//	if err := tok.Err(); err != nil {
+//		...
+//	}
+type Tokenizer struct {
+	r   io.Reader
+	buf []byte
+	pos int
+	end int
+
+	// mark is the start of a value being captured by RawValue,
+	// data after mark is kept when the buffer is refilled.
+	mark int
+
+	offset int64 // offset of buf[0] in the input
+	eof    bool
+	err    error
+
+	stack []byte // '{' or '['
+	state int
+	tok   Token
+}
+
+// NewTokenizer returns a Tokenizer which reads from r.
+func NewTokenizer(r io.Reader) *Tokenizer {
+	return &Tokenizer{
+		r:    r,
+		buf:  make([]byte, tokenizerBufSize),
+		mark: -1,
+	}
+}
+
+// NewBytesTokenizer returns a Tokenizer which reads from data,
+// token raw bytes reference data directly without copying.
+func NewBytesTokenizer(data []byte) *Tokenizer {
+	return &Tokenizer{
+		buf:  data,
+		end:  len(data),
+		mark: -1,
+		eof:  true,
+	}
+}
+
+// Token returns the current token.
+func (t *Tokenizer) Token() Token {
+	return t.tok
+}
+
+// Depth returns the current nesting depth.
+func (t *Tokenizer) Depth() int {
+	return len(t.stack)
+}
+
+// Offset returns the input offset after the current token.
+func (t *Tokenizer) Offset() int64 {
+	return t.offset + int64(t.pos)
+}
+
+// Err returns the first error encountered by the Tokenizer,
+// it returns nil if the input is consumed successfully.
+func (t *Tokenizer) Err() error {
+	if t.err == io.EOF {
+		return nil
+	}
+	return t.err
+}
+
+// Next reads the next token, it returns false when there are no more
+// tokens or an error occurs, check Err for the error.
+func (t *Tokenizer) Next() bool {
+	if t.err != nil {
+		return false
+	}
+	for {
+		c, ok := t.skipSpace()
+		if !ok {
+			if t.err == nil {
+				t.err = io.EOF
+				if len(t.stack) > 0 || t.state == stColon {
+					t.err = io.ErrUnexpectedEOF
+				}
+			}
+			t.tok = Token{}
+			return false
+		}
+		switch t.state {
+		case stCommaOrEnd:
+			top := t.stack[len(t.stack)-1]
+			if c == ',' {
+				t.pos++
+				t.state = stValue
+				if top == '{' {
+					t.state = stKey
+				}
+				continue
+			}
+			if c == top+2 { // '}' == '{'+2, ']' == '['+2
+				return t.readEnd(c)
+			}
+			return t.fail("invalid character " + strconv.QuoteRune(rune(c)) + " after value")
+		case stColon:
+			if c != ':' {
+				return t.fail("invalid character " + strconv.QuoteRune(rune(c)) + " after object key")
+			}
+			t.pos++
+			t.state = stValue
+			continue
+		case stKeyOrEnd, stKey:
+			if c == '}' && t.state == stKeyOrEnd {
+				return t.readEnd(c)
+			}
+			if c != '"' {
+				return t.fail("invalid character " + strconv.QuoteRune(rune(c)) + " looking for object key")
+			}
+			if !t.readString(KeyToken) {
+				return false
+			}
+			t.state = stColon
+			return true
+		case stValueOrEnd, stValue:
+			if c == ']' && t.state == stValueOrEnd {
+				return t.readEnd(c)
+			}
+			return t.readValue(c)
+		}
+	}
+}
+
+func (t *Tokenizer) readValue(c byte) bool {
+	switch {
+	case c == '{' || c == '[':
+		kind, state := ObjectStart, stKeyOrEnd
+		if c == '[' {
+			kind, state = ArrayStart, stValueOrEnd
+		}
+		t.setToken(kind, t.pos, t.pos+1)
+		t.stack = append(t.stack, c)
+		t.state = state
+		return true
+	case c == '"':
+		if !t.readString(StringToken) {
+			return false
+		}
+	case c == '-' || ('0' <= c && c <= '9'):
+		if !t.readNumber() {
+			return false
+		}
+	case c == 't':
+		if !t.readLiteral("true", TrueToken) {
+			return false
+		}
+	case c == 'f':
+		if !t.readLiteral("false", FalseToken) {
+			return false
+		}
+	case c == 'n':
+		if !t.readLiteral("null", NullToken) {
+			return false
+		}
+	default:
+		return t.fail("invalid character " + strconv.QuoteRune(rune(c)) + " looking for value")
+	}
+	t.afterValue()
+	return true
+}
+
+func (t *Tokenizer) readEnd(c byte) bool {
+	kind := ObjectEnd
+	if c == ']' {
+		kind = ArrayEnd
+	}
+	t.stack = t.stack[:len(t.stack)-1]
+	t.setToken(kind, t.pos, t.pos+1)
+	t.afterValue()
+	return true
+}
+
+func (t *Tokenizer) afterValue() {
+	if len(t.stack) == 0 {
+		t.state = stValue
+	} else {
+		t.state = stCommaOrEnd
+	}
+}
+
+func (t *Tokenizer) setToken(kind TokenKind, start, end int) {
+	t.tok = Token{
+		Kind:   kind,
+		Raw:    t.buf[start:end],
+		Offset: t.offset + int64(start),
+		Depth:  len(t.stack),
+	}
+	t.pos = end
+}
+
+func (t *Tokenizer) readString(kind TokenKind) bool {
+	i := t.pos + 1
+	for {
+		if i >= t.end {
+			off := i - t.pos
+			if !t.fill() {
+				return t.failEOF()
+			}
+			i = t.pos + off
+			continue
+		}
+		c := t.buf[i]
+		switch {
+		case c == '"':
+			t.setToken(kind, t.pos, i+1)
+			return true
+		case c == '\\':
+			// make sure the escaped character is in the buffer
+			for i+1 >= t.end {
+				off := i - t.pos
+				if !t.fill() {
+					return t.failEOF()
+				}
+				i = t.pos + off
+			}
+			i += 2
+		case c < 0x20:
+			t.pos = i
+			return t.fail("invalid control character in string")
+		default:
+			i++
+		}
+	}
+}
+
+func (t *Tokenizer) readNumber() bool {
+	i := t.pos
+	for {
+		if i >= t.end {
+			off := i - t.pos
+			if !t.fill() {
+				if t.err != nil {
+					return false
+				}
+				break
+			}
+			i = t.pos + off
+			continue
+		}
+		c := t.buf[i]
+		if !('0' <= c && c <= '9' || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E') {
+			break
+		}
+		i++
+	}
+	if !isValidNumber(t.buf[t.pos:i]) {
+		return t.fail("invalid number " + strconv.Quote(string(t.buf[t.pos:i])))
+	}
+	i, ok := t.checkTopLevelEnd(i)
+	if !ok {
+		return false
+	}
+	t.setToken(NumberToken, t.pos, i)
+	return true
+}
+
+func (t *Tokenizer) readLiteral(lit string, kind TokenKind) bool {
+	for t.end-t.pos < len(lit) {
+		if !t.fill() {
+			return t.failEOF()
+		}
+	}
+	if string(t.buf[t.pos:t.pos+len(lit)]) != lit {
+		return t.fail("invalid literal, expecting " + lit)
+	}
+	end, ok := t.checkTopLevelEnd(t.pos + len(lit))
+	if !ok {
+		return false
+	}
+	t.setToken(kind, t.pos, end)
+	return true
+}
+
+// checkTopLevelEnd checks that a top-level number or literal ending at
+// end is followed by whitespace or EOF, else it cannot be told apart
+// from the next value, e.g. "truefalse" or "1true".
+// It returns end adjusted to the refilled buffer.
+func (t *Tokenizer) checkTopLevelEnd(end int) (int, bool) {
+	if len(t.stack) > 0 {
+		return end, true
+	}
+	for end >= t.end {
+		off := end - t.pos
+		if !t.fill() {
+			return t.pos + off, t.err == nil
+		}
+		end = t.pos + off
+	}
+	switch c := t.buf[end]; c {
+	case ' ', '\t', '\n', '\r':
+		return end, true
+	default:
+		t.pos = end
+		return end, t.fail("invalid character " + strconv.QuoteRune(rune(c)) + " after top-level value")
+	}
+}
+
+// isValidNumber checks s against the JSON number grammar.
+func isValidNumber(s []byte) bool {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	if i >= len(s) {
+		return false
+	}
+	switch {
+	case s[i] == '0':
+		i++
+	case '1' <= s[i] && s[i] <= '9':
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+	default:
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		if i >= len(s) || s[i] < '0' || s[i] > '9' {
+			return false
+		}
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if i >= len(s) || s[i] < '0' || s[i] > '9' {
+			return false
+		}
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+	}
+	return i == len(s)
+}
+
+// skipSpace skips whitespace and returns the next byte without
+// consuming it, it returns false if there is no more data.
+func (t *Tokenizer) skipSpace() (byte, bool) {
+	for {
+		for t.pos < t.end {
+			c := t.buf[t.pos]
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				return c, true
+			}
+			t.pos++
+		}
+		if !t.fill() {
+			return 0, false
+		}
+	}
+}
+
+// fill reads more data into the buffer, data before t.pos (or t.mark
+// if it is set) may be discarded, callers must adjust indexes relative
+// to t.pos.
+// It returns false if there is no more data or an error occurs.
+func (t *Tokenizer) fill() bool {
+	if t.eof || t.err != nil {
+		return false
+	}
+	keep := t.pos
+	if t.mark >= 0 && t.mark < keep {
+		keep = t.mark
+	}
+	if keep > 0 {
+		copy(t.buf, t.buf[keep:t.end])
+		t.end -= keep
+		t.pos -= keep
+		if t.mark >= 0 {
+			t.mark -= keep
+		}
+		t.offset += int64(keep)
+	}
+	if t.end == len(t.buf) {
+		newBuf := make([]byte, 2*len(t.buf))
+		copy(newBuf, t.buf[:t.end])
+		t.buf = newBuf
+	}
+	for {
+		n, err := t.r.Read(t.buf[t.end:])
+		t.end += n
+		if err != nil {
+			if err == io.EOF {
+				t.eof = true
+			} else {
+				t.err = err
+			}
+			return n > 0
+		}
+		if n > 0 {
+			return true
+		}
+	}
+}
+
+func (t *Tokenizer) fail(msg string) bool {
+	t.err = &SyntaxError{Offset: t.offset + int64(t.pos), Msg: msg}
+	t.tok = Token{}
+	return false
+}
+
+func (t *Tokenizer) failEOF() bool {
+	if t.err == nil {
+		t.err = io.ErrUnexpectedEOF
+	}
+	t.tok = Token{}
+	return false
+}
+
+// Skip skips the value of the current token.
+// If the current token is a key, its value is skipped.
+// If the current token is ObjectStart or ArrayStart, the tokens until
+// the matching end are skipped. Otherwise, it does nothing.
+func (t *Tokenizer) Skip() error {
+	if t.tok.Kind == KeyToken {
+		if !t.Next() {
+			return t.errOrUnexpectedEOF()
+		}
+	}
+	if t.tok.Kind != ObjectStart && t.tok.Kind != ArrayStart {
+		return nil
+	}
+	depth := t.tok.Depth
+	for t.Next() {
+		if (t.tok.Kind == ObjectEnd || t.tok.Kind == ArrayEnd) && t.tok.Depth == depth {
+			return nil
+		}
+	}
+	return t.errOrUnexpectedEOF()
+}
+
+// RawValue returns the raw bytes of the value of the current token.
+// If the current token is a key, the raw bytes of its value are
+// returned. If the current token is ObjectStart or ArrayStart, the
+// whole object or array is read and returned, else the raw bytes of
+// the current token are returned.
+//
+// The returned bytes are only valid until the next call to
+// Tokenizer's methods.
+func (t *Tokenizer) RawValue() ([]byte, error) {
+	if t.tok.Kind == KeyToken {
+		if !t.Next() {
+			return nil, t.errOrUnexpectedEOF()
+		}
+	}
+	if t.tok.Kind != ObjectStart && t.tok.Kind != ArrayStart {
+		if t.tok.Kind == InvalidToken || t.tok.Kind == ObjectEnd || t.tok.Kind == ArrayEnd {
+			return nil, fmt.Errorf("json: no value at %s token", t.tok.Kind)
+		}
+		return t.tok.Raw, nil
+	}
+	t.mark = t.pos - 1
+	defer func() { t.mark = -1 }()
+	if err := t.Skip(); err != nil {
+		return nil, err
+	}
+	return t.buf[t.mark:t.pos], nil
+}
+
+func (t *Tokenizer) errOrUnexpectedEOF() error {
+	if err := t.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
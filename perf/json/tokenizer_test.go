@@ -0,0 +1,240 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tokenResult struct {
+	Kind  TokenKind
+	Raw   string
+	Depth int
+}
+
+func collectTokens(t *testing.T, tok *Tokenizer) []tokenResult {
+	var out []tokenResult
+	for tok.Next() {
+		x := tok.Token()
+		out = append(out, tokenResult{x.Kind, string(x.Raw), x.Depth})
+	}
+	require.Nil(t, tok.Err())
+	return out
+}
+
+func TestTokenizer(t *testing.T) {
+	data := `{"a": 1, "b": [true, false, null, "s\"x"], "c": {}, "d": [], "e": -1.5e3}`
+	want := []tokenResult{
+		{ObjectStart, "{", 0},
+		{KeyToken, `"a"`, 1},
+		{NumberToken, "1", 1},
+		{KeyToken, `"b"`, 1},
+		{ArrayStart, "[", 1},
+		{TrueToken, "true", 2},
+		{FalseToken, "false", 2},
+		{NullToken, "null", 2},
+		{StringToken, `"s\"x"`, 2},
+		{ArrayEnd, "]", 1},
+		{KeyToken, `"c"`, 1},
+		{ObjectStart, "{", 1},
+		{ObjectEnd, "}", 1},
+		{KeyToken, `"d"`, 1},
+		{ArrayStart, "[", 1},
+		{ArrayEnd, "]", 1},
+		{KeyToken, `"e"`, 1},
+		{NumberToken, "-1.5e3", 1},
+		{ObjectEnd, "}", 0},
+	}
+
+	got := collectTokens(t, NewBytesTokenizer([]byte(data)))
+	assert.Equal(t, want, got)
+
+	// Read one byte a time to exercise buffer refilling.
+	got = collectTokens(t, NewTokenizer(iotest.OneByteReader(strings.NewReader(data))))
+	assert.Equal(t, want, got)
+}
+
+func TestTokenizer_Values(t *testing.T) {
+	tok := NewBytesTokenizer([]byte(`["aé\n", 123, 4.5]`))
+	require.True(t, tok.Next())
+
+	require.True(t, tok.Next())
+	s, err := tok.Token().Unquote()
+	require.Nil(t, err)
+	assert.Equal(t, "aé\n", s)
+
+	require.True(t, tok.Next())
+	i, err := tok.Token().Int()
+	require.Nil(t, err)
+	assert.Equal(t, int64(123), i)
+
+	require.True(t, tok.Next())
+	f, err := tok.Token().Float()
+	require.Nil(t, err)
+	assert.Equal(t, 4.5, f)
+}
+
+func TestTokenizer_Invalid(t *testing.T) {
+	testcases := []struct {
+		data   string
+		syntax bool
+	}{
+		{`{"a" 1}`, true},
+		{`{"a": 1,}`, true},
+		{`[1 2]`, true},
+		{`{1: 2}`, true},
+		{`[01]`, true},
+		{`[1.]`, true},
+		{`[tru]`, true},
+		{`["a` + "\n" + `"]`, true},
+		{`]`, true},
+		{`truefalse`, true},
+		{`1true`, true},
+		{`null"a"`, true},
+		{`{"a": [1, 2`, false},
+		{`"abc`, false},
+	}
+	for _, tc := range testcases {
+		for _, tok := range []*Tokenizer{
+			NewBytesTokenizer([]byte(tc.data)),
+			NewTokenizer(iotest.OneByteReader(strings.NewReader(tc.data))),
+		} {
+			for tok.Next() {
+			}
+			err := tok.Err()
+			if tc.syntax {
+				var syntaxErr *SyntaxError
+				assert.ErrorAs(t, err, &syntaxErr, tc.data)
+			} else {
+				assert.Equal(t, io.ErrUnexpectedEOF, err, tc.data)
+			}
+		}
+	}
+}
+
+func TestTokenizer_TopLevelValues(t *testing.T) {
+	data := "1 true\n{}[]\"a\"\"b\"\tnull"
+	want := []TokenKind{NumberToken, TrueToken, ObjectStart, ObjectEnd,
+		ArrayStart, ArrayEnd, StringToken, StringToken, NullToken}
+	for _, tok := range []*Tokenizer{
+		NewBytesTokenizer([]byte(data)),
+		NewTokenizer(iotest.OneByteReader(strings.NewReader(data))),
+	} {
+		var got []TokenKind
+		for _, x := range collectTokens(t, tok) {
+			got = append(got, x.Kind)
+		}
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestTokenizer_SkipAndRawValue(t *testing.T) {
+	data := `{"skip": {"x": [1, {"y": 2}]}, "raw": {"z": [3, 4]}, "last": "v"}`
+	for _, tok := range []*Tokenizer{
+		NewBytesTokenizer([]byte(data)),
+		NewTokenizer(iotest.OneByteReader(strings.NewReader(data))),
+	} {
+		require.True(t, tok.Next())
+		require.True(t, tok.Next())
+		require.Nil(t, tok.Skip())
+
+		require.True(t, tok.Next())
+		raw, err := tok.RawValue()
+		require.Nil(t, err)
+		assert.Equal(t, `{"z": [3, 4]}`, string(raw))
+
+		require.True(t, tok.Next())
+		assert.Equal(t, `"last"`, string(tok.Token().Raw))
+		raw, err = tok.RawValue()
+		require.Nil(t, err)
+		assert.Equal(t, `"v"`, string(raw))
+
+		require.True(t, tok.Next())
+		assert.Equal(t, ObjectEnd, tok.Token().Kind)
+		assert.False(t, tok.Next())
+		assert.Nil(t, tok.Err())
+	}
+}
+
+func TestTokenizer_NDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&buf, `{"id": %d, "name": "user-%d", "tags": ["a", "b"]}`+"\n", i, i)
+	}
+	tok := NewTokenizer(&buf)
+	count := 0
+	for tok.Next() {
+		if tok.Token().Kind == ObjectStart && tok.Token().Depth == 0 {
+			raw, err := tok.RawValue()
+			require.Nil(t, err)
+			assert.Equal(t, fmt.Sprintf("user-%d", count), Get(raw, "name").String())
+			count++
+		}
+	}
+	require.Nil(t, tok.Err())
+	assert.Equal(t, 10000, count)
+	assert.Equal(t, tokenizerBufSize, len(tok.buf))
+}
+
+func TestTokenizer_LargeToken(t *testing.T) {
+	long := strings.Repeat("x", 3*tokenizerBufSize)
+	data := `["` + long + `", 1]`
+	tok := NewTokenizer(strings.NewReader(data))
+	require.True(t, tok.Next())
+	require.True(t, tok.Next())
+	s, err := tok.Token().Unquote()
+	require.Nil(t, err)
+	assert.Equal(t, long, s)
+	require.True(t, tok.Next())
+	assert.Equal(t, "1", string(tok.Token().Raw))
+}
+
+func TestGet(t *testing.T) {
+	data := []byte(`{
+		"name": {"first": "Tom", "last": "Anderson"},
+		"age": 37,
+		"score": 9.5,
+		"active": true,
+		"nothing": null,
+		"children": ["Sara", "Alex", "Jack"],
+		"friends": [
+			{"first": "Dale", "nets": ["ig", "fb", "tw"]},
+			{"first": "Roger", "nets": ["fb", "tw"]}
+		],
+		"a.b": "dotted",
+		"esc\"aped": 1
+	}`)
+
+	assert.Equal(t, "Anderson", Get(data, "name.last").String())
+	assert.Equal(t, int64(37), Get(data, "age").Int())
+	assert.Equal(t, 9.5, Get(data, "score").Float())
+	assert.Equal(t, int64(9), Get(data, "score").Int())
+	assert.True(t, Get(data, "active").Bool())
+	assert.True(t, Get(data, "nothing").Exists())
+	assert.Equal(t, "", Get(data, "nothing").String())
+	assert.Equal(t, "Alex", Get(data, "children.1").String())
+	assert.Equal(t, "tw", Get(data, "friends.1.nets.1").String())
+	assert.Equal(t, "dotted", Get(data, `a\.b`).String())
+	assert.Equal(t, int64(1), Get(data, `esc"aped`).Int())
+	assert.Equal(t, `["fb", "tw"]`, Get(data, "friends.1.nets").String())
+	assert.Equal(t, ArrayStart, Get(data, "children").Kind)
+
+	for _, path := range []string{"missing", "name.middle", "children.3", "children.x", "age.x", "friends.-1"} {
+		assert.False(t, Get(data, path).Exists(), path)
+	}
+	assert.False(t, Get([]byte(`{"a": `), "a").Exists())
+	assert.False(t, Get([]byte(`{"b": garbage, "a": 1}`), "a").Exists())
+
+	// Data after the value is not validated.
+	assert.Equal(t, int64(1), Get([]byte(`{"a": 1, garbage`), "a").Int())
+
+	var children []string
+	require.Nil(t, Get(data, "children").Unmarshal(&children))
+	assert.Equal(t, []string{"Sara", "Alex", "Jack"}, children)
+}
@@ -2,7 +2,6 @@ package lru
 
 import (
 	"sync"
-	"sync/atomic"
 	"time"
 	"unsafe"
 )
@@ -13,8 +12,11 @@ const maxCapacity = 1<<32 - 1
 // memory will be immediately allocated. For best performance, the memory
 // will be reused and won't be freed for the lifetime of the cache.
 //
-// Param capacity must be smaller than 2^32, else it will panic.
-func NewCache[K comparable, V any](capacity int) *Cache[K, V] {
+// Param capacity must be non-negative and smaller than 2^32, else it will panic.
+func NewCache[K comparable, V any](capacity int, opts ...Option[K, V]) *Cache[K, V] {
+	if capacity < 0 {
+		panic("invalid negative capacity")
+	}
 	if capacity > maxCapacity {
 		panic("invalid too large capacity")
 	}
@@ -24,6 +26,9 @@ func NewCache[K comparable, V any](capacity int) *Cache[K, V] {
 		m:    make(map[K]uint32, capacity),
 		buf:  unsafe.Pointer(newWalBuf()),
 	}
+	for _, o := range opts {
+		o(&c.opts)
+	}
 	return c
 }
 
@@ -37,6 +42,14 @@ type Cache[K comparable, V any] struct {
 	m    map[K]uint32
 
 	buf unsafe.Pointer // *walbuf
+
+	opts    options[K, V]
+	weight  int64
+	evicted []evictedEntry[K, V]
+
+	// gen is increased when the list is reallocated by Resize,
+	// walbufs swapped out before that are discarded.
+	gen uint64
 }
 
 func (c *Cache[K, V]) Len() (n int) {
@@ -160,55 +173,74 @@ func (c *Cache[K, V]) mget(notStale bool, nowNano int64, keys ...K) map[K]V {
 }
 
 func (c *Cache[K, V]) Set(key K, value V, ttl time.Duration) {
+	nowNano := timeNowNano()
 	var expires int64
 	if ttl > 0 {
-		expires = time.Now().UnixNano() + int64(ttl)
+		expires = nowNano + int64(ttl)
 	}
 	c.mu.Lock()
 	c.checkAndFlushBuf()
-	c.set(key, value, expires)
-	c.mu.Unlock()
+	c.set(key, value, expires, nowNano)
+	c.unlockAndNotify()
 }
 
 func (c *Cache[K, V]) MSet(kvmap map[K]V, ttl time.Duration) {
+	nowNano := timeNowNano()
 	var expires int64
 	if ttl > 0 {
-		expires = time.Now().UnixNano() + int64(ttl)
+		expires = nowNano + int64(ttl)
 	}
 
 	c.mu.Lock()
 	c.checkAndFlushBuf()
 	for key, val := range kvmap {
-		c.set(key, val, expires)
+		c.set(key, val, expires, nowNano)
 	}
-	c.mu.Unlock()
+	c.unlockAndNotify()
 }
 
-func (c *Cache[K, V]) set(k K, v V, expires int64) {
+func (c *Cache[K, V]) set(k K, v V, expires int64, nowNano int64) {
+	var weight int64
+	if c.opts.weigher != nil {
+		weight = c.opts.weigher(k, v)
+	}
 	idx, exists := c.m[k]
 	if exists {
 		e := c.list.get(idx)
+		if c.opts.onEvict != nil {
+			c.evicted = append(c.evicted, evictedEntry[K, V]{k, e.value.(V), EvictReplaced})
+		}
 		e.value = v
 		e.expires = expires
+		c.weight += weight - e.weight
+		e.weight = weight
 		c.list.MoveToFront(e)
 	} else {
-		e := c.list.Back()
-		if e.key != nil {
-			delete(c.m, e.key.(K))
+		e := c.list.alloc()
+		if e == nil {
+			back := c.list.Back()
+			if back == nil { // zero capacity
+				return
+			}
+			c.evict(back, EvictCapacity, nowNano)
+			e = c.list.alloc()
 		}
 		e.key = k
 		e.value = v
 		e.expires = expires
+		e.weight = weight
+		c.weight += weight
 		c.m[k] = e.index
-		c.list.MoveToFront(e)
+		c.list.PushFront(e)
 	}
+	c.evictOverweight(nowNano)
 }
 
 func (c *Cache[K, V]) Delete(key K) {
 	c.mu.Lock()
 	c.checkAndFlushBuf()
 	c.del(key)
-	c.mu.Unlock()
+	c.unlockAndNotify()
 }
 
 func (c *Cache[K, V]) MDelete(keys ...K) {
@@ -217,61 +249,33 @@ func (c *Cache[K, V]) MDelete(keys ...K) {
 	for _, key := range keys {
 		c.del(key)
 	}
-	c.mu.Unlock()
+	c.unlockAndNotify()
 }
 
 func (c *Cache[K, V]) del(key K) {
 	idx, exists := c.m[key]
 	if exists {
-		delete(c.m, key)
-		elem := c.list.get(idx)
-		elem.key = nil
-		elem.value = nil
-		c.list.MoveToBack(elem)
+		c.evict(c.list.get(idx), EvictDeleted, 0)
 	}
 }
 
 func (c *Cache[K, V]) promote(idx uint32) {
-	buf := (*walbuf)(atomic.LoadPointer(&c.buf))
-	i := atomic.AddInt32(&buf.p, 1)
-	if i <= walBufSize {
-		buf.b[i-1] = idx
+	oldbuf := addToWalBuf(&c.buf, idx)
+	if oldbuf == nil {
 		return
 	}
 
-	// buffer is full, swap buffer
-	oldbuf := buf
-
-	// create new buffer, and reserve the first element to use for
-	// this promotion request
-	newbuf := newWalBuf()
-	newbuf.p = 1
-	for {
-		swapped := atomic.CompareAndSwapPointer(&c.buf, unsafe.Pointer(oldbuf), unsafe.Pointer(newbuf))
-		if swapped {
-			newbuf.b[0] = idx
-			break
-		}
-
-		// try again
-		oldbuf = (*walbuf)(atomic.LoadPointer(&c.buf))
-		i = atomic.AddInt32(&oldbuf.p, 1)
-		if i <= walBufSize {
-			oldbuf.b[i-1] = idx
-			newbuf.p = 0
-			walbufpool.Put(newbuf)
-			return
-		}
-	}
-
-	// the oldbuf has been swapped, we take responsibility to flush it
-	go func(c *Cache[K, V], buf *walbuf) {
+	// the oldbuf has been swapped, we take responsibility to flush it,
+	// it is discarded if the list has been reallocated by Resize
+	go func(c *Cache[K, V], buf *walbuf, gen uint64) {
 		c.mu.Lock()
-		c.flushBuf(buf)
+		if c.gen == gen {
+			c.flushBuf(buf)
+		}
 		c.mu.Unlock()
 		buf.reset()
 		walbufpool.Put(buf)
-	}(c, oldbuf)
+	}(c, oldbuf, c.gen)
 }
 
 func (c *Cache[K, V]) checkAndFlushBuf() {
@@ -293,7 +297,10 @@ func (c *Cache[K, V]) flushBuf(buf *walbuf) {
 	// promote elements by their access order
 	for _, idx := range b {
 		elem := c.list.get(idx)
-		c.list.MoveToFront(elem)
+		// skip elements which have been removed after promotion
+		if elem.key != nil {
+			c.list.MoveToFront(elem)
+		}
 	}
 }
 
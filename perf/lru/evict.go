@@ -0,0 +1,175 @@
+package lru
+
+// EvictReason tells why an entry is removed from the cache.
+type EvictReason int
+
+const (
+	// EvictCapacity means the entry is evicted because the cache is
+	// full, either by number of entries or by total weight.
+	EvictCapacity EvictReason = iota + 1
+
+	// EvictExpired means the entry is removed because it is expired.
+	EvictExpired
+
+	// EvictDeleted means the entry is deleted explicitly.
+	EvictDeleted
+
+	// EvictReplaced means the entry's value is replaced by a new value.
+	EvictReplaced
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictExpired:
+		return "expired"
+	case EvictDeleted:
+		return "deleted"
+	case EvictReplaced:
+		return "replaced"
+	}
+	return "unknown"
+}
+
+// Option customizes a Cache or ShardedCache.
+type Option[K comparable, V any] func(*options[K, V])
+
+type options[K comparable, V any] struct {
+	onEvict   func(key K, value V, reason EvictReason)
+	weigher   func(key K, value V) int64
+	maxWeight int64
+}
+
+// WithOnEvict sets a callback which is called when an entry is removed
+// from the cache, or its value is replaced.
+//
+// The callback is called after the cache lock is released, thus it is
+// safe to access the cache in the callback.
+// An expired entry which is evicted by capacity is reported with
+// EvictExpired.
+func WithOnEvict[K comparable, V any](f func(key K, value V, reason EvictReason)) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.onEvict = f
+	}
+}
+
+// WithWeigher limits the total weight of entries in the cache,
+// weigher tells the weight of an entry, e.g. size in bytes.
+// When the total weight exceeds maxWeight, least recently used entries
+// are evicted. An entry heavier than maxWeight is evicted immediately
+// after it is set.
+//
+// The capacity passed to NewCache still limits the number of entries,
+// it should be large enough to hold the expected number of entries
+// within maxWeight.
+// For ShardedCache, maxWeight limits each bucket.
+func WithWeigher[K comparable, V any](maxWeight int64, weigher func(key K, value V) int64) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.maxWeight = maxWeight
+		o.weigher = weigher
+	}
+}
+
+type evictedEntry[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictReason
+}
+
+// evict removes elem from the cache and records it for the
+// OnEvict callback.
+func (c *Cache[K, V]) evict(elem *element, reason EvictReason, nowNano int64) {
+	key := elem.key.(K)
+	if c.opts.onEvict != nil {
+		if reason == EvictCapacity && elem.isExpired(nowNano) {
+			reason = EvictExpired
+		}
+		c.evicted = append(c.evicted, evictedEntry[K, V]{key, elem.value.(V), reason})
+	}
+	delete(c.m, key)
+	c.weight -= elem.weight
+	c.list.release(elem)
+}
+
+// evictOverweight evicts least recently used entries until the total
+// weight is within limit.
+func (c *Cache[K, V]) evictOverweight(nowNano int64) {
+	if c.opts.weigher == nil {
+		return
+	}
+	for c.weight > c.opts.maxWeight && c.list.len > 0 {
+		c.evict(c.list.Back(), EvictCapacity, nowNano)
+	}
+}
+
+// unlockAndNotify releases the write lock and calls the OnEvict
+// callback for evicted entries.
+func (c *Cache[K, V]) unlockAndNotify() {
+	evicted := c.evicted
+	c.evicted = nil
+	c.mu.Unlock()
+	for _, e := range evicted {
+		c.opts.onEvict(e.key, e.value, e.reason)
+	}
+}
+
+// Weight returns the total weight of entries in the cache,
+// it is always zero if the cache is not created with WithWeigher.
+func (c *Cache[K, V]) Weight() (w int64) {
+	c.mu.RLock()
+	w = c.weight
+	c.mu.RUnlock()
+	return
+}
+
+// Resize changes the capacity of the cache, least recently used
+// entries are evicted if the new capacity is smaller than the number
+// of entries.
+//
+// It allocates new underlying memory and copies the entries,
+// thus it should not be called frequently.
+// Param capacity must be non-negative and smaller than 2^32, else it will panic.
+func (c *Cache[K, V]) Resize(capacity int) {
+	if capacity < 0 {
+		panic("invalid negative capacity")
+	}
+	if capacity > maxCapacity {
+		panic("invalid too large capacity")
+	}
+	nowNano := timeNowNano()
+	c.mu.Lock()
+	c.checkAndFlushBuf()
+	for c.list.len > capacity {
+		c.evict(c.list.Back(), EvictCapacity, nowNano)
+	}
+	newList := newList(capacity)
+	m := make(map[K]uint32, capacity)
+	for e := c.list.Back(); e != nil && e != c.list.root; e = c.list.get(e.prev) {
+		ne := newList.alloc()
+		ne.key, ne.value = e.key, e.value
+		ne.expires, ne.weight = e.expires, e.weight
+		newList.PushFront(ne)
+		m[e.key.(K)] = ne.index
+	}
+	c.list = newList
+	c.m = m
+	c.gen++
+	c.unlockAndNotify()
+}
+
+// Resize changes the capacity of each bucket, see Cache.Resize.
+func (c *ShardedCache[K, V]) Resize(bucketCapacity int) {
+	for _, c := range c.cache {
+		c.Resize(bucketCapacity)
+	}
+}
+
+// Weight returns the total weight of entries in all buckets,
+// see Cache.Weight.
+func (c *ShardedCache[K, V]) Weight() (w int64) {
+	for _, c := range c.cache {
+		w += c.Weight()
+	}
+	return
+}
@@ -0,0 +1,194 @@
+package lru
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type evictRecord struct {
+	key    string
+	value  string
+	reason EvictReason
+}
+
+func TestOnEvict(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var records []evictRecord
+	c := NewCache[string, string](3, WithOnEvict(func(key string, value string, reason EvictReason) {
+		mu.Lock()
+		records = append(records, evictRecord{key, value, reason})
+		mu.Unlock()
+	}))
+
+	c.Set("a", "va", time.Millisecond)
+	c.Set("b", "vb", time.Minute)
+	c.Set("c", "vc", time.Minute)
+	c.Set("b", "vb2", time.Minute)
+	c.Delete("c")
+	c.Delete("missing")
+	time.Sleep(2 * time.Millisecond)
+	c.MSet(map[string]string{"d": "vd", "e": "ve"}, time.Minute)
+	c.Set("f", "vf", time.Minute)
+
+	want := []evictRecord{
+		{"b", "vb", EvictReplaced},
+		{"c", "vc", EvictDeleted},
+		{"a", "va", EvictExpired},
+		{"b", "vb2", EvictCapacity},
+	}
+	if fmt.Sprint(records) != fmt.Sprint(want) {
+		t.Errorf("unexpected evictions: want= %v, got= %v", want, records)
+	}
+	if l := c.Len(); l != 3 {
+		t.Errorf("invalid length, want= 3, got= %v", l)
+	}
+}
+
+func TestOnEvictReentrant(t *testing.T) {
+	t.Parallel()
+	var c *Cache[int, int]
+	c = NewCache[int, int](2, WithOnEvict(func(key int, value int, reason EvictReason) {
+		// The callback is called without holding the lock.
+		c.Has(key)
+	}))
+	for i := 0; i < 10; i++ {
+		c.Set(i, i, 0)
+	}
+}
+
+func TestWeigher(t *testing.T) {
+	t.Parallel()
+	var evicted []string
+	c := NewCache[string, []byte](100,
+		WithWeigher(10, func(key string, value []byte) int64 {
+			return int64(len(value))
+		}),
+		WithOnEvict(func(key string, value []byte, reason EvictReason) {
+			if reason == EvictCapacity {
+				evicted = append(evicted, key)
+			}
+		}),
+	)
+
+	c.Set("a", make([]byte, 4), 0)
+	c.Set("b", make([]byte, 4), 0)
+	if w := c.Weight(); w != 8 {
+		t.Errorf("invalid weight, want= 8, got= %v", w)
+	}
+	c.Get("a")
+	c.Set("c", make([]byte, 4), 0)
+	if _, ok, _ := c.Get("b"); ok {
+		t.Error("expecting element B to be evicted")
+	}
+	c.Set("a", make([]byte, 2), 0)
+	if w := c.Weight(); w != 6 {
+		t.Errorf("invalid weight, want= 6, got= %v", w)
+	}
+	c.Set("big", make([]byte, 11), 0)
+	if c.Len() != 0 || c.Weight() != 0 {
+		t.Errorf("expecting all elements evicted, len= %v, weight= %v", c.Len(), c.Weight())
+	}
+	if fmt.Sprint(evicted) != "[b c a big]" {
+		t.Errorf("unexpected evictions: %v", evicted)
+	}
+}
+
+func TestResize(t *testing.T) {
+	t.Parallel()
+	var evicted []int
+	c := NewCache[int, int](10, WithOnEvict(func(key int, value int, reason EvictReason) {
+		evicted = append(evicted, key)
+	}))
+	for i := 0; i < 10; i++ {
+		c.Set(i, i, 0)
+	}
+	c.Get(0)
+
+	c.Resize(5)
+	if l := c.Len(); l != 5 {
+		t.Errorf("invalid length, want= 5, got= %v", l)
+	}
+	if fmt.Sprint(evicted) != "[1 2 3 4 5]" {
+		t.Errorf("unexpected evictions: %v", evicted)
+	}
+	for _, k := range []int{0, 6, 7, 8, 9} {
+		if v, ok, _ := c.Get(k); !ok || v != k {
+			t.Errorf("expecting element %v to exist", k)
+		}
+	}
+
+	c.Resize(20)
+	for i := 10; i < 25; i++ {
+		c.Set(i, i, 0)
+	}
+	if l := c.Len(); l != 20 {
+		t.Errorf("invalid length, want= 20, got= %v", l)
+	}
+
+	sc := NewShardedCache[int, int](4, 10)
+	for i := 0; i < 100; i++ {
+		sc.Set(i, i, 0)
+	}
+	sc.Resize(2)
+	if l := sc.Len(); l > 8 {
+		t.Errorf("invalid length, want <= 8, got= %v", l)
+	}
+}
+
+func TestZeroCapacity(t *testing.T) {
+	t.Parallel()
+	c := NewCache[int, int](4)
+	c.Set(1, 1, 0)
+	c.Resize(0)
+	c.Set(2, 2, 0)
+	c.MSet(map[int]int{3: 3, 4: 4}, 0)
+	if l := c.Len(); l != 0 {
+		t.Errorf("invalid length, want= 0, got= %v", l)
+	}
+	if _, ok, _ := c.Get(2); ok {
+		t.Error("expecting element 2 to not exist")
+	}
+
+	c = NewCache[int, int](0)
+	c.Set(1, 1, 0)
+	if l := c.Len(); l != 0 {
+		t.Errorf("invalid length, want= 0, got= %v", l)
+	}
+}
+
+func TestNegativeCapacity(t *testing.T) {
+	t.Parallel()
+	for _, f := range []func(){
+		func() { NewCache[int, int](-1) },
+		func() { NewCache[int, int](4).Resize(-1) },
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != "invalid negative capacity" {
+					t.Errorf("unexpected panic: %v", r)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestResizeConcurrentGet(t *testing.T) {
+	t.Parallel()
+	c := createFilledCache(time.Minute)
+	s := createRandInts(50000)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			c.Resize(500 + i*50)
+		}
+	}()
+	runConcurrentGetTest(t, c, s)
+	wg.Wait()
+}
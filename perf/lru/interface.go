@@ -4,6 +4,7 @@ import "time"
 
 var _ Interface[int, string] = (*Cache[int, string])(nil)
 var _ Interface[string, string] = (*ShardedCache[string, string])(nil)
+var _ Interface[string, string] = (*TinyLFUCache[string, string])(nil)
 
 // Interface is a generic abstract interface of the LRU cache implemented
 // in this package.
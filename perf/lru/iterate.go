@@ -0,0 +1,178 @@
+package lru
+
+// Range calls f sequentially for each unexpired entry in the cache,
+// from the most recently used to the least recently used.
+// If f returns false, Range stops the iteration.
+//
+// Range does not change the LRU order, the entries are copied while
+// holding the lock, thus f may access the cache.
+// Note that all entries are copied even if f stops early, which blocks
+// writers of the cache for O(n) time, use RangeN to visit only the
+// most recently used entries.
+func (c *Cache[K, V]) Range(f func(key K, value V) bool) {
+	entries := c.snapshot(timeNowNano())
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !f(entries[i].Key, entries[i].Value) {
+			return
+		}
+	}
+}
+
+// RangeN is like Range, but it visits at most n most recently used
+// unexpired entries, only these entries are copied while holding
+// the lock.
+func (c *Cache[K, V]) RangeN(n int, f func(key K, value V) bool) {
+	for _, e := range c.head(n, timeNowNano()) {
+		if !f(e.Key, e.Value) {
+			return
+		}
+	}
+}
+
+// head returns at most n most recently used unexpired entries,
+// from the most recently used to the least recently used.
+func (c *Cache[K, V]) head(n int, nowNano int64) []Entry[K, V] {
+	if n <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkAndFlushBuf()
+	out := make([]Entry[K, V], 0, min(n, c.list.len))
+	for e := c.list.Front(); e != nil && e != c.list.root && len(out) < n; e = c.list.get(e.next) {
+		if !e.isExpired(nowNano) {
+			out = append(out, Entry[K, V]{Key: e.key.(K), Value: e.value.(V)})
+		}
+	}
+	return out
+}
+
+// Keys returns keys of unexpired entries in the cache, from the most
+// recently used to the least recently used.
+// Like Range, it copies all entries while holding the lock.
+func (c *Cache[K, V]) Keys() []K {
+	entries := c.snapshot(timeNowNano())
+	keys := make([]K, len(entries))
+	for i := range entries {
+		keys[len(entries)-1-i] = entries[i].Key
+	}
+	return keys
+}
+
+// DeleteFunc removes entries for which f returns true, expired entries
+// are also checked. It returns the number of removed entries.
+//
+// f is called while holding the lock, it must not access the cache.
+func (c *Cache[K, V]) DeleteFunc(f func(key K, value V) bool) (n int) {
+	c.mu.Lock()
+	c.checkAndFlushBuf()
+	for e := c.list.Back(); e != nil && e != c.list.root; {
+		prev := c.list.get(e.prev)
+		if f(e.key.(K), e.value.(V)) {
+			c.evict(e, EvictDeleted, 0)
+			n++
+		}
+		e = prev
+	}
+	c.unlockAndNotify()
+	return n
+}
+
+// Purge removes all entries from the cache.
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	c.checkAndFlushBuf()
+	for c.list.len > 0 {
+		c.evict(c.list.Back(), EvictDeleted, 0)
+	}
+	c.unlockAndNotify()
+}
+
+// PurgeExpired removes expired entries from the cache,
+// it returns the number of removed entries.
+func (c *Cache[K, V]) PurgeExpired() (n int) {
+	nowNano := timeNowNano()
+	c.mu.Lock()
+	c.checkAndFlushBuf()
+	for e := c.list.Back(); e != nil && e != c.list.root; {
+		prev := c.list.get(e.prev)
+		if e.isExpired(nowNano) {
+			c.evict(e, EvictExpired, nowNano)
+			n++
+		}
+		e = prev
+	}
+	c.unlockAndNotify()
+	return n
+}
+
+// Range calls f sequentially for each unexpired entry in the cache,
+// see Cache.Range for details.
+// Buckets are iterated one by one, entries in a bucket are iterated
+// from the most recently used to the least recently used.
+func (c *ShardedCache[K, V]) Range(f func(key K, value V) bool) {
+	for _, c := range c.cache {
+		stopped := false
+		c.Range(func(key K, value V) bool {
+			stopped = !f(key, value)
+			return !stopped
+		})
+		if stopped {
+			return
+		}
+	}
+}
+
+// RangeN is like Range, but it visits at most n unexpired entries,
+// see Cache.RangeN for details.
+func (c *ShardedCache[K, V]) RangeN(n int, f func(key K, value V) bool) {
+	for _, c := range c.cache {
+		if n <= 0 {
+			return
+		}
+		stopped := false
+		c.RangeN(n, func(key K, value V) bool {
+			n--
+			stopped = !f(key, value)
+			return !stopped
+		})
+		if stopped {
+			return
+		}
+	}
+}
+
+// Keys returns keys of unexpired entries in the cache,
+// in the same order as Range.
+func (c *ShardedCache[K, V]) Keys() []K {
+	var keys []K
+	for _, c := range c.cache {
+		keys = append(keys, c.Keys()...)
+	}
+	return keys
+}
+
+// DeleteFunc removes entries for which f returns true,
+// see Cache.DeleteFunc for details.
+func (c *ShardedCache[K, V]) DeleteFunc(f func(key K, value V) bool) (n int) {
+	for _, c := range c.cache {
+		n += c.DeleteFunc(f)
+	}
+	return n
+}
+
+// Purge removes all entries from the cache.
+func (c *ShardedCache[K, V]) Purge() {
+	for _, c := range c.cache {
+		c.Purge()
+	}
+}
+
+// PurgeExpired removes expired entries from the cache,
+// it returns the number of removed entries.
+func (c *ShardedCache[K, V]) PurgeExpired() (n int) {
+	for _, c := range c.cache {
+		n += c.PurgeExpired()
+	}
+	return n
+}
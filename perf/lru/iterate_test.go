@@ -0,0 +1,134 @@
+package lru
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRangeAndKeys(t *testing.T) {
+	t.Parallel()
+	c := NewCache[string, int](10)
+	c.Set("a", 1, 0)
+	c.Set("b", 2, 0)
+	c.Set("c", 3, time.Nanosecond)
+	c.Set("d", 4, 0)
+	c.Get("a")
+	time.Sleep(time.Millisecond)
+
+	if keys := c.Keys(); fmt.Sprint(keys) != "[a d b]" {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+
+	var visited []string
+	c.Range(func(key string, value int) bool {
+		visited = append(visited, fmt.Sprintf("%s=%d", key, value))
+		c.Has(key) // f may access the cache
+		return len(visited) < 2
+	})
+	if fmt.Sprint(visited) != "[a=1 d=4]" {
+		t.Errorf("unexpected visited entries: %v", visited)
+	}
+
+	// Range does not change the LRU order.
+	if keys := c.Keys(); fmt.Sprint(keys) != "[a d b]" {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+
+	visited = visited[:0]
+	c.RangeN(2, func(key string, value int) bool {
+		visited = append(visited, fmt.Sprintf("%s=%d", key, value))
+		return true
+	})
+	if fmt.Sprint(visited) != "[a=1 d=4]" {
+		t.Errorf("unexpected visited entries: %v", visited)
+	}
+	c.RangeN(0, func(key string, value int) bool {
+		t.Error("unexpected call of f")
+		return true
+	})
+}
+
+func TestDeleteFuncAndPurge(t *testing.T) {
+	t.Parallel()
+	var reasons []string
+	c := NewCache[string, int](10, WithOnEvict(func(key string, value int, reason EvictReason) {
+		reasons = append(reasons, key+":"+reason.String())
+	}))
+	c.Set("t1:a", 1, 0)
+	c.Set("t2:a", 2, 0)
+	c.Set("t1:b", 3, time.Nanosecond)
+	c.Set("t2:b", 4, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	n := c.DeleteFunc(func(key string, value int) bool {
+		return strings.HasPrefix(key, "t1:")
+	})
+	if n != 2 || c.Len() != 2 {
+		t.Errorf("unexpected result: n= %v, len= %v", n, c.Len())
+	}
+	if n = c.PurgeExpired(); n != 1 || c.Len() != 1 {
+		t.Errorf("unexpected result: n= %v, len= %v", n, c.Len())
+	}
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("invalid length, want= 0, got= %v", c.Len())
+	}
+	want := "[t1:a:deleted t1:b:deleted t2:b:expired t2:a:deleted]"
+	if fmt.Sprint(reasons) != want {
+		t.Errorf("unexpected evictions: %v", reasons)
+	}
+
+	// The cache is still usable after purged.
+	for i := 0; i < 20; i++ {
+		c.Set(fmt.Sprint(i), i, 0)
+	}
+	if c.Len() != 10 {
+		t.Errorf("invalid length, want= 10, got= %v", c.Len())
+	}
+}
+
+func TestShardedRangeAndDelete(t *testing.T) {
+	t.Parallel()
+	c := NewShardedCache[int, int](4, 100)
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, 0)
+	}
+	keys := c.Keys()
+	sort.Ints(keys)
+	if len(keys) != 100 || keys[0] != 0 || keys[99] != 99 {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+
+	count := 0
+	c.Range(func(key int, value int) bool {
+		count++
+		return count < 10
+	})
+	if count != 10 {
+		t.Errorf("expecting Range to stop, count= %v", count)
+	}
+
+	count = 0
+	c.RangeN(30, func(key int, value int) bool {
+		count++
+		return true
+	})
+	if count != 30 {
+		t.Errorf("expecting RangeN to visit 30 entries, count= %v", count)
+	}
+
+	n := c.DeleteFunc(func(key int, value int) bool { return key%2 == 0 })
+	if n != 50 || c.Len() != 50 {
+		t.Errorf("unexpected result: n= %v, len= %v", n, c.Len())
+	}
+	if n = c.PurgeExpired(); n != 0 {
+		t.Errorf("unexpected purged count: %v", n)
+	}
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("invalid length, want= 0, got= %v", c.Len())
+	}
+}
@@ -8,7 +8,9 @@ type element struct {
 	key, value any
 
 	expires int64 // nanosecond timestamp
+	weight  int64
 	index   uint32
+	segment uint8
 }
 
 func (e *element) isExpired(nowNano int64) bool {
@@ -16,25 +18,42 @@ func (e *element) isExpired(nowNano int64) bool {
 }
 
 func newList(capacity int) *list {
-	elems := make([]element, capacity+1)
+	return newSegmentedList(capacity, 1)
+}
+
+// newSegmentedList creates a list which holds multiple segments,
+// elems[0:segments] are roots of the segments.
+// Methods Front, Back, PushFront, PushBack, MoveToFront and MoveToBack
+// operate on the first segment.
+func newSegmentedList(capacity int, segments int) *list {
+	elems := make([]element, capacity+segments)
 	l := &list{
 		elems: elems,
 		root:  &elems[0],
+		free:  make([]uint32, 0, capacity),
+	}
+	for i := 0; i < segments; i++ {
+		e := &elems[i]
+		e.index = uint32(i)
+		e.next, e.prev = e.index, e.index
 	}
 
 	size := len(elems)
-	for i := 1; i < size; i++ {
+	for i := size - 1; i >= segments; i-- {
 		e := &elems[i]
 		e.index = uint32(i)
-		l.PushBack(e)
+		l.free = append(l.free, e.index)
 	}
 	return l
 }
 
+// list is a doubly linked list of elements in use, unused elements
+// are kept in the free list.
 type list struct {
 	elems []element
 	root  *element
 	len   int
+	free  []uint32
 }
 
 func (l *list) Front() *element {
@@ -67,6 +86,28 @@ func (l *list) MoveToBack(elem *element) {
 	l.insert(l.remove(elem), l.get(l.root.prev))
 }
 
+// alloc takes an unused element from the free list,
+// it returns nil if there is no unused element.
+func (l *list) alloc() *element {
+	n := len(l.free)
+	if n == 0 {
+		return nil
+	}
+	elem := l.get(l.free[n-1])
+	l.free = l.free[:n-1]
+	return elem
+}
+
+// release removes elem from the list and puts it into the free list.
+func (l *list) release(elem *element) {
+	l.remove(elem)
+	elem.key = nil
+	elem.value = nil
+	elem.expires = 0
+	elem.weight = 0
+	l.free = append(l.free, elem.index)
+}
+
 func (l *list) insert(elem, at *element) *element {
 	next := l.get(at.next)
 	at.next = elem.index
@@ -0,0 +1,336 @@
+package lru
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by LoadingCache.Get if the key is absent from
+// the result of a BatchLoader call which loads the key.
+var ErrNotFound = errors.New("lru: value not found")
+
+var errLoaderPanic = errors.New("lru: loader panicked")
+
+// LoadingOptions configures a LoadingCache.
+type LoadingOptions[K comparable, V any] struct {
+
+	// Loader loads the value for a key when it is missing or expired
+	// in the cache, it is required.
+	Loader func(ctx context.Context, key K) (V, error)
+
+	// BatchLoader optionally loads values for multiple keys, it is used
+	// by LoadingCache.MGet to load missing values in one call.
+	// Keys absent from the returned map are treated as not found and
+	// are not cached.
+	// If it is nil, Loader is called for each missing key.
+	BatchLoader func(ctx context.Context, keys []K) (map[K]V, error)
+
+	// TTL specifies the TTL of loaded values, zero means no expiration.
+	TTL time.Duration
+
+	// AsyncRefresh makes Get and MGet return expired values immediately
+	// and refresh them in background, instead of blocking to load new
+	// values. Missing values are still loaded synchronously.
+	AsyncRefresh bool
+
+	// OnRefreshError optionally specifies a function to report errors
+	// of background refreshing, the stale values are kept in the cache.
+	OnRefreshError func(keys []K, err error)
+}
+
+// LoadingCache wraps a Cache or ShardedCache, it loads values by the
+// configured loader when they are missing or expired.
+//
+// Concurrent loading of a same key is deduplicated, only one loader
+// call is in-flight for a key at a time, other callers wait for the
+// result of the in-flight call.
+type LoadingCache[K comparable, V any] struct {
+	cache Interface[K, V]
+	opts  LoadingOptions[K, V]
+	group loadGroup[K, V]
+}
+
+// NewLoadingCache returns a LoadingCache which wraps cache.
+// It panics if opts.Loader is nil.
+func NewLoadingCache[K comparable, V any](cache Interface[K, V], opts LoadingOptions[K, V]) *LoadingCache[K, V] {
+	if opts.Loader == nil {
+		panic("lru: LoadingOptions.Loader is required")
+	}
+	return &LoadingCache[K, V]{
+		cache: cache,
+		opts:  opts,
+	}
+}
+
+// Cache returns the underlying cache, it can be used to set or delete
+// values directly.
+func (c *LoadingCache[K, V]) Cache() Interface[K, V] {
+	return c.cache
+}
+
+// Get returns the value for key, it loads the value if it is missing
+// or expired in the cache.
+// If AsyncRefresh is enabled, an expired value is returned immediately
+// and refreshed in background.
+func (c *LoadingCache[K, V]) Get(ctx context.Context, key K) (V, error) {
+	v, exists, expired := c.cache.Get(key)
+	if exists && !expired {
+		return v, nil
+	}
+	if exists && c.opts.AsyncRefresh {
+		c.refresh(ctx, []K{key})
+		return v, nil
+	}
+	return c.group.do(key, func() (V, error) {
+		// Another call may have loaded the value before we got here.
+		if v, exists := c.cache.GetNotStale(key); exists {
+			return v, nil
+		}
+		v, err := c.opts.Loader(ctx, key)
+		if err != nil {
+			return v, err
+		}
+		c.cache.Set(key, v, c.opts.TTL)
+		return v, nil
+	})
+}
+
+// MGet returns values for keys, missing or expired values are loaded
+// by BatchLoader in one call if it is configured, else by Loader one
+// by one.
+// If AsyncRefresh is enabled, expired values are returned immediately
+// and refreshed in background.
+//
+// Keys failed to load are absent from the result, the returned error
+// joins errors from the loader calls.
+func (c *LoadingCache[K, V]) MGet(ctx context.Context, keys ...K) (map[K]V, error) {
+	res := c.cache.MGetNotStale(keys...)
+	if len(res) == len(keys) {
+		return res, nil
+	}
+	var missing, stale []K
+	for _, key := range keys {
+		if _, ok := res[key]; ok {
+			continue
+		}
+		if c.opts.AsyncRefresh {
+			if v, exists, _ := c.cache.GetQuiet(key); exists {
+				res[key] = v
+				stale = append(stale, key)
+				continue
+			}
+		}
+		missing = append(missing, key)
+	}
+	if len(stale) > 0 {
+		c.refresh(ctx, stale)
+	}
+	if len(missing) == 0 {
+		return res, nil
+	}
+	loaded, err := c.group.doBatch(missing, func(keys []K) (map[K]V, error) {
+		return c.load(ctx, keys)
+	})
+	for k, v := range loaded {
+		res[k] = v
+	}
+	return res, err
+}
+
+// load loads values for keys and saves them into the cache.
+func (c *LoadingCache[K, V]) load(ctx context.Context, keys []K) (map[K]V, error) {
+	if c.opts.BatchLoader != nil {
+		res, err := c.opts.BatchLoader(ctx, keys)
+		if len(res) > 0 {
+			c.cache.MSet(res, c.opts.TTL)
+		}
+		return res, err
+	}
+	res := make(map[K]V, len(keys))
+	var errs []error
+	for _, key := range keys {
+		v, err := c.opts.Loader(ctx, key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		res[key] = v
+	}
+	if len(res) > 0 {
+		c.cache.MSet(res, c.opts.TTL)
+	}
+	return res, errors.Join(errs...)
+}
+
+// refresh reloads keys in background, keys which are being loaded
+// are skipped.
+func (c *LoadingCache[K, V]) refresh(ctx context.Context, keys []K) {
+	ctx = context.WithoutCancel(ctx)
+	calls := c.group.start(keys)
+	if len(calls) == 0 {
+		return
+	}
+	go func() {
+		keys := make([]K, 0, len(calls))
+		for k := range calls {
+			keys = append(keys, k)
+		}
+		var res map[K]V
+		var err error
+		defer func() {
+			// Release the waiters and report the error even if the
+			// loader panics, there is no caller to propagate the panic to.
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%w: %v", errLoaderPanic, r)
+			}
+			c.group.finish(calls, res, err)
+			if err != nil && c.opts.OnRefreshError != nil {
+				c.opts.OnRefreshError(keys, err)
+			}
+		}()
+		if len(keys) == 1 {
+			var v V
+			v, err = c.opts.Loader(ctx, keys[0])
+			if err == nil {
+				res = map[K]V{keys[0]: v}
+				c.cache.Set(keys[0], v, c.opts.TTL)
+			}
+		} else {
+			res, err = c.load(ctx, keys)
+		}
+	}()
+}
+
+// loadGroup is like golang.org/x/sync/singleflight.Group, but it also
+// supports loading multiple keys in one call.
+type loadGroup[K comparable, V any] struct {
+	mu sync.Mutex
+	m  map[K]*loadCall[V]
+}
+
+type loadCall[V any] struct {
+	done  chan struct{}
+	val   V
+	found bool
+
+	// errp points to the error of the loader call, calls finished by
+	// a same batch share the pointer, thus errors can be deduplicated
+	// without comparing them.
+	errp *error
+}
+
+func (c *loadCall[V]) result() (V, error) {
+	<-c.done
+	if c.found {
+		return c.val, nil
+	}
+	if *c.errp == nil {
+		return c.val, ErrNotFound
+	}
+	return c.val, *c.errp
+}
+
+// start registers calls for keys which are not in-flight,
+// it returns the registered calls.
+func (g *loadGroup[K, V]) start(keys []K) map[K]*loadCall[V] {
+	calls, _ := g.startOrWait(keys)
+	return calls
+}
+
+// startOrWait registers calls for keys which are not in-flight,
+// it returns the registered calls and the in-flight calls of other keys.
+func (g *loadGroup[K, V]) startOrWait(keys []K) (calls, waits map[K]*loadCall[V]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.m == nil {
+		g.m = make(map[K]*loadCall[V])
+	}
+	for _, key := range keys {
+		if c, ok := g.m[key]; ok {
+			if waits == nil {
+				waits = make(map[K]*loadCall[V])
+			}
+			waits[key] = c
+			continue
+		}
+		if calls == nil {
+			calls = make(map[K]*loadCall[V])
+		}
+		c := &loadCall[V]{done: make(chan struct{})}
+		g.m[key] = c
+		calls[key] = c
+	}
+	return
+}
+
+// finish completes calls with the loaded values and error.
+func (g *loadGroup[K, V]) finish(calls map[K]*loadCall[V], res map[K]V, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	errp := &err
+	for key, c := range calls {
+		c.val, c.found = res[key]
+		c.errp = errp
+		if g.m[key] == c {
+			delete(g.m, key)
+		}
+		close(c.done)
+	}
+}
+
+func (g *loadGroup[K, V]) do(key K, fn func() (V, error)) (V, error) {
+	calls, waits := g.startOrWait([]K{key})
+	if c := waits[key]; c != nil {
+		return c.result()
+	}
+	var res map[K]V
+	err := errLoaderPanic
+	defer func() {
+		// Make sure the waiters are released even if fn panics.
+		g.finish(calls, res, err)
+	}()
+	v, err := fn()
+	if err == nil {
+		res = map[K]V{key: v}
+	}
+	return v, err
+}
+
+func (g *loadGroup[K, V]) doBatch(keys []K, fn func(keys []K) (map[K]V, error)) (map[K]V, error) {
+	calls, waits := g.startOrWait(keys)
+	var res map[K]V
+	var err error
+	if len(calls) > 0 {
+		err = errLoaderPanic
+		func() {
+			defer func() { g.finish(calls, res, err) }()
+			keys := make([]K, 0, len(calls))
+			for k := range calls {
+				keys = append(keys, k)
+			}
+			res, err = fn(keys)
+		}()
+	}
+	if len(waits) == 0 {
+		return res, err
+	}
+	out := make(map[K]V, len(keys))
+	for k, v := range res {
+		out[k] = v
+	}
+	errs := []error{err}
+	seen := make(map[*error]bool)
+	for key, c := range waits {
+		v, e := c.result()
+		if e == nil {
+			out[key] = v
+		} else if e != ErrNotFound && !seen[c.errp] {
+			seen[c.errp] = true
+			errs = append(errs, e)
+		}
+	}
+	return out, errors.Join(errs...)
+}
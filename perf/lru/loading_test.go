@@ -0,0 +1,256 @@
+package lru
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadingCacheGet(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	c := NewLoadingCache[int, int](NewCache[int, int](100), LoadingOptions[int, int]{
+		Loader: func(ctx context.Context, key int) (int, error) {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(10 * time.Millisecond)
+			if key < 0 {
+				return 0, errors.New("negative key")
+			}
+			return key * 10, nil
+		},
+		TTL: time.Minute,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.Get(context.Background(), 1)
+			if err != nil || v != 10 {
+				t.Errorf("unexpected result: v= %v, err= %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expecting concurrent loads to be deduplicated, calls= %v", n)
+	}
+
+	if _, err := c.Get(context.Background(), -1); err == nil {
+		t.Error("expecting loader error")
+	}
+	if _, exists, _ := c.Cache().Get(-1); exists {
+		t.Error("expecting failed value not cached")
+	}
+}
+
+func TestLoadingCacheMGet(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var batches [][]int
+	c := NewLoadingCache[int, int](NewShardedCache[int, int](4, 100), LoadingOptions[int, int]{
+		Loader: func(ctx context.Context, key int) (int, error) {
+			return key * 10, nil
+		},
+		BatchLoader: func(ctx context.Context, keys []int) (map[int]int, error) {
+			sort.Ints(keys)
+			mu.Lock()
+			batches = append(batches, keys)
+			mu.Unlock()
+			res := make(map[int]int)
+			for _, k := range keys {
+				if k != 4 {
+					res[k] = k * 10
+				}
+			}
+			return res, nil
+		},
+		TTL: time.Minute,
+	})
+	c.Cache().Set(1, 10, time.Minute)
+
+	res, err := c.MGet(context.Background(), 1, 2, 3, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res) != 3 || res[1] != 10 || res[2] != 20 || res[3] != 30 {
+		t.Errorf("unexpected result: %v", res)
+	}
+	if len(batches) != 1 || len(batches[0]) != 3 {
+		t.Errorf("expecting one batch of missing keys, got %v", batches)
+	}
+	if _, err = c.Get(context.Background(), 2); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(batches) != 1 {
+		t.Errorf("expecting value loaded by MGet to be cached")
+	}
+}
+
+func TestLoadingCacheAsyncRefresh(t *testing.T) {
+	t.Parallel()
+	var version int32
+	refreshed := make(chan struct{}, 10)
+	c := NewLoadingCache[string, int32](NewCache[string, int32](100), LoadingOptions[string, int32]{
+		Loader: func(ctx context.Context, key string) (int32, error) {
+			defer func() { refreshed <- struct{}{} }()
+			return atomic.AddInt32(&version, 1), nil
+		},
+		BatchLoader: func(ctx context.Context, keys []string) (map[string]int32, error) {
+			defer func() { refreshed <- struct{}{} }()
+			v := atomic.AddInt32(&version, 1)
+			res := make(map[string]int32)
+			for _, k := range keys {
+				res[k] = v
+			}
+			return res, nil
+		},
+		TTL:          20 * time.Millisecond,
+		AsyncRefresh: true,
+	})
+
+	v, err := c.Get(context.Background(), "a")
+	if err != nil || v != 1 {
+		t.Fatalf("unexpected result: v= %v, err= %v", v, err)
+	}
+	<-refreshed
+	time.Sleep(30 * time.Millisecond)
+
+	// The stale value is returned and refreshed in background.
+	v, err = c.Get(context.Background(), "a")
+	if err != nil || v != 1 {
+		t.Fatalf("expecting stale value, v= %v, err= %v", v, err)
+	}
+	<-refreshed
+	time.Sleep(time.Millisecond)
+	if v, _ = c.Cache().GetNotStale("a"); v != 2 {
+		t.Errorf("expecting value to be refreshed, got %v", v)
+	}
+
+	c.Cache().Set("b", 0, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	res, err := c.MGet(context.Background(), "a", "b")
+	if err != nil || res["a"] != 2 || res["b"] != 0 {
+		t.Errorf("unexpected result: res= %v, err= %v", res, err)
+	}
+	<-refreshed
+	time.Sleep(time.Millisecond)
+	if v, _ = c.Cache().GetNotStale("b"); v != 3 {
+		t.Errorf("expecting value to be refreshed, got %v", v)
+	}
+}
+
+func TestLoadGroupBatch(t *testing.T) {
+	t.Parallel()
+	var g loadGroup[int, int]
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go g.do(1, func() (int, error) {
+		close(started)
+		<-release
+		return 100, nil
+	})
+	<-started
+
+	var loaded []int
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	res, err := g.doBatch([]int{1, 2, 3}, func(keys []int) (map[int]int, error) {
+		loaded = append(loaded, keys...)
+		return map[int]int{2: 20}, errors.New("key 3 failed")
+	})
+	if err == nil {
+		t.Error("expecting batch error")
+	}
+	if len(loaded) != 2 {
+		t.Errorf("expecting in-flight key not loaded again, loaded= %v", loaded)
+	}
+	if len(res) != 2 || res[1] != 100 || res[2] != 20 {
+		t.Errorf("unexpected result: %v", res)
+	}
+}
+
+func TestLoadingCacheRefreshPanic(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	reported := make(chan error, 1)
+	c := NewLoadingCache[string, int](NewCache[string, int](100), LoadingOptions[string, int]{
+		Loader: func(ctx context.Context, key string) (int, error) {
+			if atomic.AddInt32(&calls, 1) > 1 {
+				panic("refresh failed")
+			}
+			return 1, nil
+		},
+		TTL:          time.Millisecond,
+		AsyncRefresh: true,
+		OnRefreshError: func(keys []string, err error) {
+			reported <- err
+		},
+	})
+
+	if v, err := c.Get(context.Background(), "a"); err != nil || v != 1 {
+		t.Fatalf("unexpected result: v= %v, err= %v", v, err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if v, err := c.Get(context.Background(), "a"); err != nil || v != 1 {
+		t.Fatalf("expecting stale value, v= %v, err= %v", v, err)
+	}
+	select {
+	case err := <-reported:
+		if !errors.Is(err, errLoaderPanic) {
+			t.Errorf("expecting loader panic error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expecting refresh error to be reported")
+	}
+
+	// The in-flight call is finished, the key can be loaded again.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() { recover() }()
+		c.Cache().Delete("a")
+		c.Get(context.Background(), "a")
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Get blocked by an unfinished refresh call")
+	}
+}
+
+type sliceError []string
+
+func (e sliceError) Error() string { return strings.Join(e, ",") }
+
+func TestLoadGroupBatchUncomparableError(t *testing.T) {
+	t.Parallel()
+	var g loadGroup[int, int]
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go g.doBatch([]int{1, 2}, func(keys []int) (map[int]int, error) {
+		close(started)
+		<-release
+		return nil, sliceError{"batch", "failed"}
+	})
+	<-started
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	_, err := g.doBatch([]int{1, 2, 3}, func(keys []int) (map[int]int, error) {
+		return map[int]int{3: 30}, nil
+	})
+	if err == nil || strings.Count(err.Error(), "batch,failed") != 1 {
+		t.Errorf("expecting deduplicated batch error, got %v", err)
+	}
+}
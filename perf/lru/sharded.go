@@ -12,7 +12,9 @@ import (
 // the lru cache instance returned by NewCache function.
 // Generally NewCache should be used instead of this unless you are sure that
 // you are facing the lock contention problem.
-func NewShardedCache[K comparable, V any](buckets, bucketCapacity int) *ShardedCache[K, V] {
+//
+// The options are applied to each bucket.
+func NewShardedCache[K comparable, V any](buckets, bucketCapacity int, opts ...Option[K, V]) *ShardedCache[K, V] {
 	buckets = int(internal.NextPowerOfTwo(uint(buckets)))
 	mask := uintptr(buckets - 1)
 	mc := &ShardedCache[K, V]{
@@ -21,7 +23,7 @@ func NewShardedCache[K comparable, V any](buckets, bucketCapacity int) *ShardedC
 		cache:   make([]*Cache[K, V], buckets),
 	}
 	for i := 0; i < buckets; i++ {
-		mc.cache[i] = NewCache[K, V](bucketCapacity)
+		mc.cache[i] = NewCache[K, V](bucketCapacity, opts...)
 	}
 	mc.hashFunc = rthash.NewHashFunc[K]()
 	return mc
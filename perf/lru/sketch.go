@@ -0,0 +1,79 @@
+package lru
+
+import "github.com/jxskiss/gopkg/v2/internal"
+
+// cmSketch is a count-min sketch with 4-bit counters, it estimates
+// access frequency of keys for TinyLFU admission policy.
+//
+// Counters are halved periodically to keep the frequency fresh,
+// see "TinyLFU: A Highly Efficient Cache Admission Policy".
+type cmSketch struct {
+	rows      [cmDepth][]uint64 // each uint64 holds 16 4-bit counters
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+const cmDepth = 4
+
+var cmSeeds = [cmDepth]uint64{
+	0xc3a5c85c97cb3127, 0xb492b66fbe98f273,
+	0x9ae16a3b2f90404f, 0xcbf29ce484222325,
+}
+
+func newCMSketch(capacity int) *cmSketch {
+	counters := internal.NextPowerOfTwo(uint(max(capacity, 16)))
+	s := &cmSketch{
+		mask:    uint64(counters - 1),
+		resetAt: 10 * max(capacity, 1),
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint64, counters/16)
+	}
+	return s
+}
+
+func (s *cmSketch) index(h uint64, i int) (word uint64, shift uint64) {
+	h = (h ^ cmSeeds[i]) * 0x9E3779B97F4A7C15
+	h ^= h >> 32
+	idx := h & s.mask
+	return idx >> 4, (idx & 15) << 2
+}
+
+// increment increases the frequency of h, and halves all counters
+// when the number of additions reaches the sample size.
+func (s *cmSketch) increment(h uint64) {
+	added := false
+	for i := range s.rows {
+		word, shift := s.index(h, i)
+		if (s.rows[i][word]>>shift)&0xf < 15 {
+			s.rows[i][word] += 1 << shift
+			added = true
+		}
+	}
+	if added {
+		s.additions++
+		if s.additions >= s.resetAt {
+			s.reset()
+		}
+	}
+}
+
+// estimate returns the estimated frequency of h.
+func (s *cmSketch) estimate(h uint64) int {
+	freq := uint64(15)
+	for i := range s.rows {
+		word, shift := s.index(h, i)
+		freq = min(freq, (s.rows[i][word]>>shift)&0xf)
+	}
+	return int(freq)
+}
+
+func (s *cmSketch) reset() {
+	for _, row := range s.rows {
+		for j := range row {
+			row[j] = (row[j] >> 1) & 0x7777777777777777
+		}
+	}
+	s.additions /= 2
+}
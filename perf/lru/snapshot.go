@@ -0,0 +1,164 @@
+package lru
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Entry is a cache entry written by Dump and read by Restore.
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+
+	// Expires is the time when the entry expires, the zero value means
+	// the entry never expires.
+	// It is an absolute time, thus an entry restored later still
+	// expires at the original time.
+	Expires time.Time
+}
+
+// EntryEncoder writes entries to an underlying writer.
+type EntryEncoder[K comparable, V any] interface {
+	Encode(e *Entry[K, V]) error
+}
+
+// EntryDecoder reads entries from an underlying reader,
+// it returns io.EOF when there are no more entries.
+type EntryDecoder[K comparable, V any] interface {
+	Decode(e *Entry[K, V]) error
+}
+
+// Codec creates encoders and decoders used by Dump and Restore.
+type Codec[K comparable, V any] interface {
+	NewEncoder(w io.Writer) EntryEncoder[K, V]
+	NewDecoder(r io.Reader) EntryDecoder[K, V]
+}
+
+// GobCodec encodes entries using encoding/gob.
+// If K or V are interface types, the concrete types must be registered
+// by gob.Register.
+type GobCodec[K comparable, V any] struct{}
+
+func (GobCodec[K, V]) NewEncoder(w io.Writer) EntryEncoder[K, V] {
+	return codecFunc[K, V](gob.NewEncoder(w).Encode)
+}
+
+func (GobCodec[K, V]) NewDecoder(r io.Reader) EntryDecoder[K, V] {
+	return codecFunc[K, V](gob.NewDecoder(r).Decode)
+}
+
+// JSONCodec encodes entries as a stream of JSON objects using
+// encoding/json.
+type JSONCodec[K comparable, V any] struct{}
+
+func (JSONCodec[K, V]) NewEncoder(w io.Writer) EntryEncoder[K, V] {
+	return codecFunc[K, V](json.NewEncoder(w).Encode)
+}
+
+func (JSONCodec[K, V]) NewDecoder(r io.Reader) EntryDecoder[K, V] {
+	return codecFunc[K, V](json.NewDecoder(r).Decode)
+}
+
+type codecFunc[K comparable, V any] func(v any) error
+
+func (f codecFunc[K, V]) Encode(e *Entry[K, V]) error { return f(e) }
+func (f codecFunc[K, V]) Decode(e *Entry[K, V]) error { return f(e) }
+
+// Dump writes entries of the cache to w using codec, in order from
+// the least recently used to the most recently used, thus Restore
+// recovers the LRU order. Expired entries are skipped.
+// If codec is nil, GobCodec is used.
+//
+// The entries are copied while holding the lock, and encoded after
+// the lock is released.
+func (c *Cache[K, V]) Dump(w io.Writer, codec Codec[K, V]) error {
+	return dumpEntries(w, codec, c.snapshot(timeNowNano()))
+}
+
+// Restore reads entries from r using codec and adds them to the cache,
+// entries are added in the order they are read.
+// Entries keep their original expiration time, entries which have
+// expired since they were dumped are skipped.
+// If codec is nil, GobCodec is used.
+func (c *Cache[K, V]) Restore(r io.Reader, codec Codec[K, V]) error {
+	return restoreEntries(r, codec, timeNowNano(), c.Set)
+}
+
+func (c *Cache[K, V]) snapshot(nowNano int64) []Entry[K, V] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkAndFlushBuf()
+	out := make([]Entry[K, V], 0, len(c.m))
+	for e := c.list.Back(); e != nil && e != c.list.root; e = c.list.get(e.prev) {
+		var expires time.Time
+		if e.expires > 0 {
+			if e.expires <= nowNano {
+				continue
+			}
+			expires = time.Unix(0, e.expires)
+		}
+		out = append(out, Entry[K, V]{Key: e.key.(K), Value: e.value.(V), Expires: expires})
+	}
+	return out
+}
+
+// Dump writes entries of all buckets to w using codec,
+// see Cache.Dump for details.
+// Entries are written bucket by bucket, the LRU order within each
+// bucket is kept.
+func (c *ShardedCache[K, V]) Dump(w io.Writer, codec Codec[K, V]) error {
+	nowNano := timeNowNano()
+	var entries []Entry[K, V]
+	for _, c := range c.cache {
+		entries = append(entries, c.snapshot(nowNano)...)
+	}
+	return dumpEntries(w, codec, entries)
+}
+
+// Restore reads entries from r using codec and adds them to the cache,
+// see Cache.Restore for details.
+func (c *ShardedCache[K, V]) Restore(r io.Reader, codec Codec[K, V]) error {
+	return restoreEntries(r, codec, timeNowNano(), c.Set)
+}
+
+func dumpEntries[K comparable, V any](w io.Writer, codec Codec[K, V], entries []Entry[K, V]) error {
+	if codec == nil {
+		codec = GobCodec[K, V]{}
+	}
+	enc := codec.NewEncoder(w)
+	for i := range entries {
+		if err := enc.Encode(&entries[i]); err != nil {
+			return fmt.Errorf("cannot encode cache entry: %w", err)
+		}
+	}
+	return nil
+}
+
+func restoreEntries[K comparable, V any](r io.Reader, codec Codec[K, V], nowNano int64, set func(key K, value V, ttl time.Duration)) error {
+	if codec == nil {
+		codec = GobCodec[K, V]{}
+	}
+	dec := codec.NewDecoder(r)
+	for {
+		var e Entry[K, V]
+		err := dec.Decode(&e)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("cannot decode cache entry: %w", err)
+		}
+		var ttl time.Duration
+		if !e.Expires.IsZero() {
+			ttl = time.Duration(e.Expires.UnixNano() - nowNano)
+			if ttl <= 0 {
+				continue
+			}
+		}
+		set(e.Key, e.Value, ttl)
+	}
+}
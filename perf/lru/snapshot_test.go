@@ -0,0 +1,103 @@
+package lru
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDumpRestore(t *testing.T) {
+	t.Parallel()
+	for _, codec := range []Codec[string, int]{nil, GobCodec[string, int]{}, JSONCodec[string, int]{}} {
+		c := NewCache[string, int](10)
+		c.Set("a", 1, 0)
+		c.Set("b", 2, time.Minute)
+		c.Set("c", 3, time.Nanosecond)
+		c.Set("d", 4, 0)
+		c.Get("a")
+		time.Sleep(time.Millisecond)
+
+		var buf bytes.Buffer
+		if err := c.Dump(&buf, codec); err != nil {
+			t.Fatalf("Dump: %v", err)
+		}
+
+		c2 := NewCache[string, int](3)
+		if err := c2.Restore(&buf, codec); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if l := c2.Len(); l != 3 {
+			t.Errorf("invalid length, want= 3, got= %v", l)
+		}
+		if _, exists, _ := c2.GetQuiet("c"); exists {
+			t.Error("expecting expired element C to be skipped")
+		}
+		_, _, ttl := c2.GetWithTTL("b")
+		if ttl == nil || *ttl <= 0 || *ttl > time.Minute {
+			t.Errorf("expecting TTL of element B to be restored, got %v", ttl)
+		}
+
+		// The LRU order is kept: d, a, b (promoted by GetWithTTL).
+		c2.Set("e", 5, 0)
+		if _, exists, _ := c2.GetQuiet("d"); exists {
+			t.Error("expecting element D to be evicted")
+		}
+		if v, exists, _ := c2.GetQuiet("a"); !exists || v != 1 {
+			t.Error("expecting element A to exist")
+		}
+	}
+}
+
+func TestRestoreStaleSnapshot(t *testing.T) {
+	t.Parallel()
+	c := NewCache[string, int](10)
+	c.Set("a", 1, time.Hour)
+	c.Set("b", 2, time.Minute)
+	c.Set("c", 3, 0)
+
+	var buf bytes.Buffer
+	if err := c.Dump(&buf, nil); err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+
+	// Restore the snapshot as if the process was down for 30 minutes.
+	var restored []string
+	nowNano := timeNowNano() + int64(30*time.Minute)
+	err := restoreEntries(&buf, GobCodec[string, int]{}, nowNano, func(key string, value int, ttl time.Duration) {
+		restored = append(restored, fmt.Sprintf("%s:%v", key, ttl.Round(time.Minute)))
+	})
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if fmt.Sprint(restored) != "[a:30m0s c:0s]" {
+		t.Errorf("unexpected restored entries: %v", restored)
+	}
+}
+
+func TestShardedDumpRestore(t *testing.T) {
+	t.Parallel()
+	c := NewShardedCache[int, string](4, 100)
+	for i := 0; i < 100; i++ {
+		c.Set(i, fmt.Sprint(i), time.Minute)
+	}
+
+	var buf bytes.Buffer
+	if err := c.Dump(&buf, JSONCodec[int, string]{}); err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+	c2 := NewShardedCache[int, string](4, 100)
+	if err := c2.Restore(&buf, JSONCodec[int, string]{}); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if l := c2.Len(); l != 100 {
+		t.Errorf("invalid length, want= 100, got= %v", l)
+	}
+	if v, _ := c2.GetNotStale(42); v != "42" {
+		t.Errorf("unexpected value: %v", v)
+	}
+
+	if err := c2.Restore(bytes.NewBufferString("invalid"), JSONCodec[int, string]{}); err == nil {
+		t.Error("expecting error for invalid data")
+	}
+}
@@ -0,0 +1,412 @@
+package lru
+
+import (
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/jxskiss/gopkg/v2/internal/rthash"
+)
+
+const (
+	segWindow uint8 = iota
+	segProbation
+	segProtected
+	numSegments
+)
+
+// NewTinyLFUCache returns a cache instance with given capacity which
+// uses the W-TinyLFU policy, the underlying memory will be immediately
+// allocated and reused for the lifetime of the cache, same with Cache.
+//
+// Option WithWeigher is not supported, it panics if given.
+// Param capacity must be smaller than 2^32, else it will panic.
+func NewTinyLFUCache[K comparable, V any](capacity int, opts ...Option[K, V]) *TinyLFUCache[K, V] {
+	if capacity > maxCapacity-int(numSegments) {
+		panic("invalid too large capacity")
+	}
+	windowCap := max(1, capacity/100)
+	mainCap := max(0, capacity-windowCap)
+	c := &TinyLFUCache[K, V]{
+		list:         newSegmentedList(capacity, int(numSegments)),
+		m:            make(map[K]uint32, capacity),
+		buf:          unsafe.Pointer(newWalBuf()),
+		sketch:       newCMSketch(capacity),
+		hashFunc:     rthash.NewHashFunc[K](),
+		windowCap:    windowCap,
+		protectedCap: mainCap * 8 / 10,
+	}
+	for _, o := range opts {
+		o(&c.opts)
+	}
+	if c.opts.weigher != nil {
+		panic("lru: WithWeigher is not supported by TinyLFUCache")
+	}
+	return c
+}
+
+// TinyLFUCache is an in-memory cache using the W-TinyLFU policy,
+// it is resistant to one-off scans which flush a plain LRU cache.
+//
+// New entries are admitted to a small LRU window (1% of capacity),
+// entries evicted from the window compete with the victims of the main
+// area, which is a segmented LRU (SLRU) with a probation segment and
+// a protected segment (80% of the main area). The one with higher
+// access frequency, estimated by a count-min sketch, is kept.
+//
+// Like Cache, read operations record promotions to a walbuf under
+// the read lock, access frequencies are updated when the walbuf is
+// flushed.
+//
+// It implements Interface in this package, see Interface for detailed
+// api documents.
+type TinyLFUCache[K comparable, V any] struct {
+	mu   sync.RWMutex
+	list *list
+	m    map[K]uint32
+
+	buf unsafe.Pointer // *walbuf
+
+	sketch   *cmSketch
+	hashFunc rthash.HashFunc[K]
+
+	segLen       [numSegments]int
+	windowCap    int
+	protectedCap int
+
+	opts    options[K, V]
+	evicted []evictedEntry[K, V]
+}
+
+func (c *TinyLFUCache[K, V]) Len() (n int) {
+	c.mu.RLock()
+	n = len(c.m)
+	c.mu.RUnlock()
+	return
+}
+
+func (c *TinyLFUCache[K, V]) Has(key K) (exists, expired bool) {
+	c.mu.RLock()
+	_, elem, exists := c.get(key)
+	if exists {
+		expired = elem.isExpired(timeNowNano())
+	}
+	c.mu.RUnlock()
+	return
+}
+
+func (c *TinyLFUCache[K, V]) Get(key K) (v V, exists, expired bool) {
+	c.mu.RLock()
+	idx, elem, exists := c.get(key)
+	if exists {
+		v = elem.value.(V)
+		expired = elem.isExpired(timeNowNano())
+		c.promote(idx)
+	}
+	c.mu.RUnlock()
+	return
+}
+
+func (c *TinyLFUCache[K, V]) GetWithTTL(key K) (v V, exists bool, ttl *time.Duration) {
+	c.mu.RLock()
+	idx, elem, exists := c.get(key)
+	if exists {
+		v = elem.value.(V)
+		if elem.expires > 0 {
+			x := time.Duration(elem.expires - timeNowNano())
+			ttl = &x
+		}
+		c.promote(idx)
+	}
+	c.mu.RUnlock()
+	return
+}
+
+func (c *TinyLFUCache[K, V]) GetQuiet(key K) (v V, exists, expired bool) {
+	c.mu.RLock()
+	_, elem, exists := c.get(key)
+	if exists {
+		v = elem.value.(V)
+		expired = elem.isExpired(timeNowNano())
+	}
+	c.mu.RUnlock()
+	return
+}
+
+func (c *TinyLFUCache[K, V]) GetNotStale(key K) (v V, exists bool) {
+	c.mu.RLock()
+	idx, elem, exists := c.get(key)
+	if exists {
+		expired := elem.isExpired(timeNowNano())
+		if !expired {
+			v = elem.value.(V)
+			c.promote(idx)
+		} else {
+			exists = false
+		}
+	}
+	c.mu.RUnlock()
+	return
+}
+
+func (c *TinyLFUCache[K, V]) get(key K) (idx uint32, elem *element, exists bool) {
+	idx, exists = c.m[key]
+	if exists {
+		elem = c.list.get(idx)
+	}
+	return
+}
+
+func (c *TinyLFUCache[K, V]) MGet(keys ...K) map[K]V {
+	return c.mget(false, keys...)
+}
+
+func (c *TinyLFUCache[K, V]) MGetNotStale(keys ...K) map[K]V {
+	return c.mget(true, keys...)
+}
+
+func (c *TinyLFUCache[K, V]) mget(notStale bool, keys ...K) map[K]V {
+	nowNano := timeNowNano()
+	res := make(map[K]V, len(keys))
+
+	// Split into batches to let the cache to have chance to be updated
+	// if length of keys is much larger than walBufSize.
+	total := len(keys)
+	batch := walBufSize
+	for i, j := 0, batch; i < total; i, j = i+batch, j+batch {
+		if j > total {
+			j = total
+		}
+
+		c.mu.RLock()
+		for _, key := range keys[i:j] {
+			idx, elem, exists := c.get(key)
+			if exists {
+				if notStale && elem.isExpired(nowNano) {
+					continue
+				}
+				res[key] = elem.value.(V)
+				c.promote(idx)
+			}
+		}
+		c.mu.RUnlock()
+	}
+	return res
+}
+
+func (c *TinyLFUCache[K, V]) Set(key K, value V, ttl time.Duration) {
+	nowNano := timeNowNano()
+	var expires int64
+	if ttl > 0 {
+		expires = nowNano + int64(ttl)
+	}
+	c.mu.Lock()
+	c.checkAndFlushBuf()
+	c.set(key, value, expires, nowNano)
+	c.unlockAndNotify()
+}
+
+func (c *TinyLFUCache[K, V]) MSet(kvmap map[K]V, ttl time.Duration) {
+	nowNano := timeNowNano()
+	var expires int64
+	if ttl > 0 {
+		expires = nowNano + int64(ttl)
+	}
+	c.mu.Lock()
+	c.checkAndFlushBuf()
+	for key, val := range kvmap {
+		c.set(key, val, expires, nowNano)
+	}
+	c.unlockAndNotify()
+}
+
+func (c *TinyLFUCache[K, V]) set(k K, v V, expires int64, nowNano int64) {
+	c.sketch.increment(c.hash(k))
+	if idx, exists := c.m[k]; exists {
+		e := c.list.get(idx)
+		if c.opts.onEvict != nil {
+			c.evicted = append(c.evicted, evictedEntry[K, V]{k, e.value.(V), EvictReplaced})
+		}
+		e.value = v
+		e.expires = expires
+		c.access(e)
+		return
+	}
+
+	e := c.list.alloc()
+	if e == nil {
+		c.evictOne(nowNano)
+		e = c.list.alloc()
+		if e == nil { // zero capacity
+			return
+		}
+	}
+	e.key = k
+	e.value = v
+	e.expires = expires
+	c.m[k] = e.index
+	c.pushFront(segWindow, e)
+
+	// Move entries overflowing the window to the main area,
+	// it has room since the total number of entries is limited.
+	for c.segLen[segWindow] > c.windowCap {
+		cand := c.back(segWindow)
+		c.unlink(cand)
+		c.pushFront(segProbation, cand)
+	}
+}
+
+// evictOne evicts one entry to make room for a new entry, the LRU
+// entry of the window competes with the LRU entry of the main area.
+func (c *TinyLFUCache[K, V]) evictOne(nowNano int64) {
+	cand := c.back(segWindow)
+	victim := c.back(segProbation)
+	if victim == nil {
+		victim = c.back(segProtected)
+	}
+	switch {
+	case cand == nil && victim == nil:
+		return
+	case cand == nil:
+		c.evict(victim, EvictCapacity, nowNano)
+	case victim == nil, cand.isExpired(nowNano):
+		c.evict(cand, EvictCapacity, nowNano)
+	case victim.isExpired(nowNano) ||
+		c.sketch.estimate(c.hash(cand.key.(K))) > c.sketch.estimate(c.hash(victim.key.(K))):
+		c.evict(victim, EvictCapacity, nowNano)
+		c.unlink(cand)
+		c.pushFront(segProbation, cand)
+	default:
+		c.evict(cand, EvictCapacity, nowNano)
+	}
+}
+
+// access moves e to the front of its segment, an entry in the
+// probation segment is promoted to the protected segment.
+func (c *TinyLFUCache[K, V]) access(e *element) {
+	seg := e.segment
+	if seg == segProbation {
+		seg = segProtected
+	}
+	c.unlink(e)
+	c.pushFront(seg, e)
+	for c.segLen[segProtected] > c.protectedCap {
+		demoted := c.back(segProtected)
+		c.unlink(demoted)
+		c.pushFront(segProbation, demoted)
+	}
+}
+
+func (c *TinyLFUCache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	c.checkAndFlushBuf()
+	c.del(key)
+	c.unlockAndNotify()
+}
+
+func (c *TinyLFUCache[K, V]) MDelete(keys ...K) {
+	c.mu.Lock()
+	c.checkAndFlushBuf()
+	for _, key := range keys {
+		c.del(key)
+	}
+	c.unlockAndNotify()
+}
+
+func (c *TinyLFUCache[K, V]) del(key K) {
+	if idx, exists := c.m[key]; exists {
+		c.evict(c.list.get(idx), EvictDeleted, 0)
+	}
+}
+
+func (c *TinyLFUCache[K, V]) evict(elem *element, reason EvictReason, nowNano int64) {
+	key := elem.key.(K)
+	if c.opts.onEvict != nil {
+		if reason == EvictCapacity && elem.isExpired(nowNano) {
+			reason = EvictExpired
+		}
+		c.evicted = append(c.evicted, evictedEntry[K, V]{key, elem.value.(V), reason})
+	}
+	delete(c.m, key)
+	c.segLen[elem.segment]--
+	c.list.release(elem)
+}
+
+func (c *TinyLFUCache[K, V]) unlockAndNotify() {
+	evicted := c.evicted
+	c.evicted = nil
+	c.mu.Unlock()
+	for _, e := range evicted {
+		c.opts.onEvict(e.key, e.value, e.reason)
+	}
+}
+
+func (c *TinyLFUCache[K, V]) hash(key K) uint64 {
+	return uint64(c.hashFunc(key))
+}
+
+func (c *TinyLFUCache[K, V]) back(seg uint8) *element {
+	if c.segLen[seg] == 0 {
+		return nil
+	}
+	return c.list.get(c.list.get(uint32(seg)).prev)
+}
+
+func (c *TinyLFUCache[K, V]) pushFront(seg uint8, e *element) {
+	e.segment = seg
+	c.list.insert(e, c.list.get(uint32(seg)))
+	c.segLen[seg]++
+}
+
+func (c *TinyLFUCache[K, V]) unlink(e *element) {
+	c.list.remove(e)
+	c.segLen[e.segment]--
+}
+
+func (c *TinyLFUCache[K, V]) promote(idx uint32) {
+	oldbuf := addToWalBuf(&c.buf, idx)
+	if oldbuf == nil {
+		return
+	}
+
+	// the oldbuf has been swapped, we take responsibility to flush it
+	go func(c *TinyLFUCache[K, V], buf *walbuf) {
+		c.mu.Lock()
+		c.flushBuf(buf)
+		c.mu.Unlock()
+		buf.reset()
+		walbufpool.Put(buf)
+	}(c, oldbuf)
+}
+
+func (c *TinyLFUCache[K, V]) checkAndFlushBuf() {
+	buf := (*walbuf)(c.buf)
+	if buf.p > 0 {
+		c.flushBuf(buf)
+		buf.reset()
+	}
+}
+
+func (c *TinyLFUCache[K, V]) flushBuf(buf *walbuf) {
+	if buf.p == 0 {
+		return
+	}
+
+	// record all accesses to the sketch before deduplication
+	n := min(int(buf.p), walBufSize)
+	for _, idx := range buf.b[:n] {
+		elem := c.list.get(idx)
+		if elem.key != nil {
+			c.sketch.increment(c.hash(elem.key.(K)))
+		}
+	}
+
+	// promote elements by their access order,
+	// skip elements which have been removed after promotion
+	for _, idx := range buf.deduplicate() {
+		elem := c.list.get(idx)
+		if elem.key != nil {
+			c.access(elem)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package lru
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func createFilledTinyLFUCache(ttl time.Duration) *TinyLFUCache[int64, int64] {
+	c := NewTinyLFUCache[int64, int64](1000)
+	for i := 0; i < 1000; i++ {
+		key := int64(rand.Intn(5000))
+		c.Set(key, key, ttl)
+	}
+	return c
+}
+
+func TestTinyLFUBasic(t *testing.T) {
+	t.Parallel()
+	c := NewTinyLFUCache[string, string](100)
+	if _, ok, _ := c.Get("a"); ok {
+		t.Error("a")
+	}
+
+	c.Set("a", "va", time.Minute)
+	c.MSet(map[string]string{"b": "vb", "c": "vc"}, time.Minute)
+	if v, _, _ := c.Get("a"); v != "va" {
+		t.Error("va")
+	}
+	if v, _ := c.GetNotStale("b"); v != "vb" {
+		t.Error("vb")
+	}
+	if v, _, _ := c.GetQuiet("c"); v != "vc" {
+		t.Error("vc")
+	}
+	if m := c.MGet("a", "b", "x"); len(m) != 2 || m["a"] != "va" || m["b"] != "vb" {
+		t.Errorf("expecting MGet to work, got %v", m)
+	}
+
+	c.Set("d", "vd", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if exists, expired := c.Has("d"); !exists || !expired {
+		t.Error("expecting element D to be expired")
+	}
+	if _, ok := c.GetNotStale("d"); ok {
+		t.Error("expecting GetNotStale to not return expired value")
+	}
+	if m := c.MGetNotStale("a", "d"); len(m) != 1 {
+		t.Errorf("expecting MGetNotStale to work, got %v", m)
+	}
+
+	c.Delete("a")
+	c.MDelete("b", "missing")
+	if l := c.Len(); l != 2 {
+		t.Errorf("invalid length, want= 2, got= %v", l)
+	}
+}
+
+func TestTinyLFUCapacity(t *testing.T) {
+	t.Parallel()
+	evicted := 0
+	c := NewTinyLFUCache[int, int](100, WithOnEvict(func(key int, value int, reason EvictReason) {
+		evicted++
+	}))
+	for i := 0; i < 1000; i++ {
+		c.Set(i, i, 0)
+		for j := 0; j < i%3; j++ {
+			c.Get(i)
+		}
+	}
+	if l := c.Len(); l != 100 {
+		t.Errorf("invalid length, want= 100, got= %v", l)
+	}
+	if evicted != 900 {
+		t.Errorf("invalid eviction count, want= 900, got= %v", evicted)
+	}
+	total := c.segLen[segWindow] + c.segLen[segProbation] + c.segLen[segProtected]
+	if total != 100 || c.segLen[segWindow] > c.windowCap || c.segLen[segProtected] > c.protectedCap {
+		t.Errorf("invalid segments: %v", c.segLen)
+	}
+}
+
+func TestTinyLFUScanResistant(t *testing.T) {
+	t.Parallel()
+	c := NewTinyLFUCache[int, int](100)
+	lru := NewCache[int, int](100)
+
+	// hot keys accessed frequently
+	for round := 0; round < 20; round++ {
+		for i := 0; i < 50; i++ {
+			for _, cache := range []Interface[int, int]{c, lru} {
+				if _, ok, _ := cache.Get(i); !ok {
+					cache.Set(i, i, 0)
+				}
+			}
+		}
+	}
+	// a one-off scan
+	for i := 1000; i < 2000; i++ {
+		c.Set(i, i, 0)
+		lru.Set(i, i, 0)
+	}
+
+	hot, lruHot := 0, 0
+	for i := 0; i < 50; i++ {
+		if _, ok, _ := c.GetQuiet(i); ok {
+			hot++
+		}
+		if _, ok, _ := lru.GetQuiet(i); ok {
+			lruHot++
+		}
+	}
+	if hot < 45 {
+		t.Errorf("expecting hot keys to survive the scan, got %v", hot)
+	}
+	if lruHot != 0 {
+		t.Errorf("expecting plain LRU to be flushed by the scan, got %v", lruHot)
+	}
+}
+
+func TestTinyLFUZeroCapacity(t *testing.T) {
+	t.Parallel()
+	c := NewTinyLFUCache[int, int](0)
+	c.Set(1, 1, 0)
+	if l := c.Len(); l != 0 {
+		t.Errorf("invalid length, want= 0, got= %v", l)
+	}
+}
+
+func TestTinyLFUConcurrentGetSet(t *testing.T) {
+	t.Parallel()
+	c := createFilledTinyLFUCache(time.Second)
+	s := createRandInts(50000)
+
+	runConcurrentGetTest(t, c, s)
+	runConcurrentSetTest(t, c, s)
+	runConcurrentGetSetTest(t, c, s)
+}
+
+func BenchmarkConcurrentGetTinyLFUCache(bb *testing.B) {
+	c := createFilledTinyLFUCache(time.Second)
+	s := createRandInts(5000)
+
+	bb.ResetTimer()
+	bb.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Get(s[i%5000])
+			i++
+		}
+	})
+}
+
+// BenchmarkHitRatio compares hit ratio of Cache and TinyLFUCache
+// on Zipf distributed workloads, with and without one-off scans
+// interleaved.
+func BenchmarkHitRatio(b *testing.B) {
+	const keySpace = 1 << 20
+	for _, capacity := range []int{1000, 10000} {
+		for _, scan := range []bool{false, true} {
+			name := fmt.Sprintf("cap=%d/scan=%v", capacity, scan)
+			b.Run("LRU/"+name, func(b *testing.B) {
+				runHitRatioBenchmark(b, NewCache[uint64, uint64](capacity), keySpace, scan)
+			})
+			b.Run("TinyLFU/"+name, func(b *testing.B) {
+				runHitRatioBenchmark(b, NewTinyLFUCache[uint64, uint64](capacity), keySpace, scan)
+			})
+		}
+	}
+}
+
+func runHitRatioBenchmark(b *testing.B, c Interface[uint64, uint64], keySpace uint64, scan bool) {
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.01, 1, keySpace-1)
+	scanKey := keySpace
+	hits, total := 0, 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var key uint64
+		if scan && i%4 == 0 {
+			// every fourth request is part of a sequential scan
+			// which never repeats
+			key = scanKey
+			scanKey++
+		} else {
+			key = zipf.Uint64()
+			total++
+		}
+		if _, ok, _ := c.Get(key); ok {
+			if key < keySpace {
+				hits++
+			}
+			continue
+		}
+		c.Set(key, key, 0)
+	}
+	if total > 0 {
+		b.ReportMetric(100*float64(hits)/float64(total), "hit%")
+	}
+}
@@ -1,7 +1,11 @@
 //nolint:revive
 package lru
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
 
 const (
 	// walBufSize must be power of two
@@ -32,6 +36,44 @@ func newWalBuf() *walbuf {
 //    和 flushBuf 方法的互斥性，因此保证了一个 walbuf 被传递给 flushBuf 方法后，
 //    不会被其他任何 goroutine 持有，flushBuf 结束后，可以安全放回 walbufpool 重用;
 
+// addToWalBuf records a promotion request of idx to the walbuf pointed
+// by bufp. If the walbuf is full, it is swapped by a new one and
+// returned, the caller takes responsibility to flush it.
+func addToWalBuf(bufp *unsafe.Pointer, idx uint32) (full *walbuf) {
+	buf := (*walbuf)(atomic.LoadPointer(bufp))
+	i := atomic.AddInt32(&buf.p, 1)
+	if i <= walBufSize {
+		buf.b[i-1] = idx
+		return nil
+	}
+
+	// buffer is full, swap buffer
+	oldbuf := buf
+
+	// create new buffer, and reserve the first element to use for
+	// this promotion request
+	newbuf := newWalBuf()
+	newbuf.p = 1
+	for {
+		swapped := atomic.CompareAndSwapPointer(bufp, unsafe.Pointer(oldbuf), unsafe.Pointer(newbuf))
+		if swapped {
+			newbuf.b[0] = idx
+			break
+		}
+
+		// try again
+		oldbuf = (*walbuf)(atomic.LoadPointer(bufp))
+		i = atomic.AddInt32(&oldbuf.p, 1)
+		if i <= walBufSize {
+			oldbuf.b[i-1] = idx
+			newbuf.p = 0
+			walbufpool.Put(newbuf)
+			return nil
+		}
+	}
+	return oldbuf
+}
+
 // walbuf helps to reduce lock-contention of read requests from the cache.
 type walbuf struct {
 	b [walBufSize]uint32